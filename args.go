@@ -0,0 +1,118 @@
+package gomocket
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ArgumentMatcher can be passed to WithArgs or WithNamedArgs in place of expected value
+// to match incoming arg by custom rule
+type ArgumentMatcher interface {
+	Match(v interface{}) bool
+}
+
+// TransformFunc rewrites incoming args before the mock matches them, see WithArgTransform
+type TransformFunc func([]driver.NamedValue) []driver.NamedValue
+
+type regexpArg struct {
+	re *regexp.Regexp
+}
+
+// RegexpArg matches string or []byte args against regular expression, other types never match.
+// Pattern is compiled once and panics if it is invalid
+func RegexpArg(pattern string) ArgumentMatcher {
+	return regexpArg{re: regexp.MustCompile(pattern)}
+}
+
+func (m regexpArg) Match(v interface{}) bool {
+	switch val := v.(type) {
+	case string:
+		return m.re.MatchString(val)
+	case []byte:
+		return m.re.Match(val)
+	}
+	return false
+}
+
+// structToNamedArgs reflects struct fields into name to value map. Names are taken from `db` tags
+// or field names, fields tagged with `db:"-"` and unexported ones are skipped, embedded structs are flattened
+func structToNamedArgs(v interface{}) map[string]interface{} {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("mock_catcher: expected struct for named args, got %T", v))
+	}
+	named := make(map[string]interface{})
+	collectStructArgs(rv, named)
+	return named
+}
+
+func collectStructArgs(rv reflect.Value, named map[string]interface{}) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag := strings.Split(field.Tag.Get("db"), ",")[0]
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			collectStructArgs(rv.Field(i), named)
+			continue
+		}
+		if field.PkgPath != "" { // unexported
+			continue
+		}
+		name := tag
+		if name == "" {
+			name = field.Name
+		}
+		named[name] = rv.Field(i).Interface()
+	}
+}
+
+type floatNear struct {
+	value, epsilon float64
+}
+
+// FloatNear matches float32 or float64 args which differ from value by at most epsilon,
+// other types never match
+func FloatNear(value, epsilon float64) ArgumentMatcher {
+	return floatNear{value: value, epsilon: epsilon}
+}
+
+func (m floatNear) Match(v interface{}) bool {
+	var f float64
+	switch val := v.(type) {
+	case float64:
+		f = val
+	case float32:
+		f = float64(val)
+	default:
+		return false
+	}
+	return math.Abs(f-m.value) <= m.epsilon
+}
+
+// HashArgs returns hex encoded SHA-256 of args in order, used by WithArgsHash. Every arg contributes
+// its name, Go type and value formatted with %v, time.Time values are formatted as RFC 3339 with nanoseconds.
+// Ordinals are not hashed, so args could be built without them
+func HashArgs(args []driver.NamedValue) string {
+	h := sha256.New()
+	for _, arg := range args {
+		value := arg.Value
+		if t, ok := value.(time.Time); ok {
+			value = t.Format(time.RFC3339Nano)
+		}
+		fmt.Fprintf(h, "%s\x00%T\x00%v\x00", arg.Name, arg.Value, value)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,192 @@
+package gomocket
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// errNotCaptured is returned by assertions of mocks which do not capture args
+var errNotCaptured = errors.New("mock_catcher: args are not captured, use CaptureArgs()")
+
+// Invocation is a call served by the mock, recorded when args are captured
+type Invocation struct {
+	Query string
+	Args  []driver.NamedValue
+	When  time.Time
+}
+
+// Invocations returns calls captured by CaptureArgs in order they were served
+func (fr *FakeResponse) Invocations() []Invocation {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	calls := make([]Invocation, len(fr.invocations))
+	for i, call := range fr.invocations {
+		call.Args = append([]driver.NamedValue(nil), call.Args...)
+		calls[i] = call
+	}
+	return calls
+}
+
+// invocationAt returns captured call by its index, fr.mu has to be held
+func (fr *FakeResponse) invocationAt(callIndex int) (Invocation, error) {
+	if !fr.Capture {
+		return Invocation{}, errNotCaptured
+	}
+	if callIndex < 0 || callIndex >= len(fr.invocations) {
+		return Invocation{}, fmt.Errorf("mock_catcher: call %d of %q not found, mock was called %d times", callIndex, fr.Pattern, len(fr.invocations))
+	}
+	return fr.invocations[callIndex], nil
+}
+
+// AssertArgAt returns error if arg at argIndex of call at callIndex, both 0-based, does not equal expected.
+// Args are compared the same way the mock matches them
+func (fr *FakeResponse) AssertArgAt(callIndex, argIndex int, expected interface{}) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	call, err := fr.invocationAt(callIndex)
+	if err != nil {
+		return err
+	}
+	if argIndex < 0 || argIndex >= len(call.Args) {
+		return fmt.Errorf("mock_catcher: arg %d of call %d not found, call has %d args", argIndex, callIndex, len(call.Args))
+	}
+	if actual := call.Args[argIndex].Value; !fr.isArgEqual(expected, actual) {
+		return fmt.Errorf("mock_catcher: arg %d of call %d mismatches. Expected: [%v] , Got: [%v]", argIndex, callIndex, expected, actual)
+	}
+	return nil
+}
+
+// AssertArgCountAt returns error if call at callIndex, 0-based, did not have exactly n args
+func (fr *FakeResponse) AssertArgCountAt(callIndex, n int) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	call, err := fr.invocationAt(callIndex)
+	if err != nil {
+		return err
+	}
+	if len(call.Args) != n {
+		return fmt.Errorf("mock_catcher: args count of call %d mismatches. Expected: [%v] , Got: [%v]", callIndex, n, len(call.Args))
+	}
+	return nil
+}
+
+// AssertArgSequence returns error unless captured calls had exactly the expected args in order,
+// one list of args per call. Args are compared the same way the mock matches them
+func (fr *FakeResponse) AssertArgSequence(expected [][]interface{}) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	if !fr.Capture {
+		return errNotCaptured
+	}
+	if len(fr.invocations) != len(expected) {
+		return fmt.Errorf("mock_catcher: calls count of %q mismatches. Expected: [%v] , Got: [%v]", fr.Pattern, len(expected), len(fr.invocations))
+	}
+	for i, call := range fr.invocations {
+		actual := make([]interface{}, len(call.Args))
+		for j, arg := range call.Args {
+			actual[j] = arg.Value
+		}
+		if len(actual) != len(expected[i]) {
+			return fmt.Errorf("mock_catcher: args of call %d mismatch. Expected: [%v] , Got: [%v]", i, expected[i], actual)
+		}
+		for j := range actual {
+			if !fr.isArgEqual(expected[i][j], actual[j]) {
+				return fmt.Errorf("mock_catcher: args of call %d mismatch. Expected: [%v] , Got: [%v]", i, expected[i], actual)
+			}
+		}
+	}
+	return nil
+}
+
+// WithExpectedCalls makes AssertExpectations fail unless the mock is triggered exactly n times,
+// n = 0 asserts the query never runs
+func (fr *FakeResponse) WithExpectedCalls(n int) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Expect = true
+	fr.ExpectedCalls = n
+	return fr
+}
+
+// AssertExpectations returns error describing every mock with expected calls count,
+// which was triggered different number of times
+func (mc *MockCatcher) AssertExpectations() error {
+	mc.mu.Lock()
+	mocks := append([]*FakeResponse(nil), mc.Mocks...)
+	mc.mu.Unlock()
+
+	var failures []string
+	for _, fr := range mocks {
+		fr.mu.Lock()
+		if fr.Expect && fr.triggered != fr.ExpectedCalls {
+			failures = append(failures, fmt.Sprintf("%q expected to be called %d times, called %d times", fr.Pattern, fr.ExpectedCalls, fr.triggered))
+		}
+		fr.mu.Unlock()
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("mock_catcher: expectations not met: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// AssertTxBalanced returns error listing transactions begun since last Reset,
+// which were neither committed nor rolled back
+func (mc *MockCatcher) AssertTxBalanced() error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	var leaked []string
+	for i, tx := range mc.transactions {
+		if !tx.ended {
+			leaked = append(leaked, fmt.Sprintf("#%d (%v)", i+1, sql.IsolationLevel(tx.opts.Isolation)))
+		}
+	}
+	if len(leaked) > 0 {
+		return fmt.Errorf("mock_catcher: %d of %d transactions neither committed nor rolled back: %s", len(leaked), len(mc.transactions), strings.Join(leaked, ", "))
+	}
+	return nil
+}
+
+// AssertNoUnmatched returns error listing queries since last Reset which no mock matched,
+// including ones forwarded by Passthrough
+func (mc *MockCatcher) AssertNoUnmatched() error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	var unmatched []string
+	for _, record := range mc.history {
+		if !record.Matched {
+			unmatched = append(unmatched, fmt.Sprintf("%q", record.Query))
+		}
+	}
+	if len(unmatched) > 0 {
+		return fmt.Errorf("mock_catcher: %d queries matched no mock: %s", len(unmatched), strings.Join(unmatched, ", "))
+	}
+	return nil
+}
+
+// Verify runs end of test checks at once: AssertExpectations, AssertNoUnmatched, AssertTxBalanced and TableErrors,
+// and returns single error describing every failed one
+//
+//	t.Cleanup(func() {
+//		if err := mocket.Catcher.Verify(); err != nil {
+//			t.Error(err)
+//		}
+//	})
+func (mc *MockCatcher) Verify() error {
+	var failures []string
+	for _, err := range []error{mc.AssertExpectations(), mc.AssertNoUnmatched(), mc.AssertTxBalanced()} {
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	for _, err := range mc.TableErrors() {
+		failures = append(failures, err.Error())
+	}
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package gomocket
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAssertArgs(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	fr := Catcher.Reset().NewMock().WithQuery("UPDATE users").CaptureArgs()
+
+	db.Exec("UPDATE users SET age = ? WHERE id = ?", 27, 1)
+	db.Exec("UPDATE users SET name = ?", "FirstLast")
+
+	if err := fr.AssertArgAt(0, 0, int64(27)); err != nil {
+		t.Error(err)
+	}
+	if err := fr.AssertArgAt(0, 1, int64(1)); err != nil {
+		t.Error(err)
+	}
+	if err := fr.AssertArgAt(1, 0, "FirstLast"); err != nil {
+		t.Error(err)
+	}
+	if err := fr.AssertArgCountAt(0, 2); err != nil {
+		t.Error(err)
+	}
+	if err := fr.AssertArgCountAt(1, 1); err != nil {
+		t.Error(err)
+	}
+
+	if err := fr.AssertArgAt(0, 0, int64(28)); err == nil {
+		t.Error("Mismatching arg should fail assertion")
+	}
+	if err := fr.AssertArgAt(1, 1, "FirstLast"); err == nil {
+		t.Error("Missing arg should fail assertion")
+	}
+	if err := fr.AssertArgAt(2, 0, "FirstLast"); err == nil {
+		t.Error("Missing call should fail assertion")
+	}
+	if err := fr.AssertArgCountAt(1, 2); err == nil {
+		t.Error("Mismatching args count should fail assertion")
+	}
+
+	if err := Catcher.NewMock().AssertArgCountAt(0, 0); err != errNotCaptured {
+		t.Errorf("Assertion without capture mismatches. Expected: [%v] , Got: [%v]", errNotCaptured, err)
+	}
+}
+
+func TestAssertArgSequence(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	fr := Catcher.Reset().NewMock().WithQuery("UPDATE users").CaptureArgs()
+
+	for _, id := range []int64{1, 2, 3} {
+		db.Exec("UPDATE users SET visited = ? WHERE id = ?", true, id)
+	}
+	if err := fr.AssertArgSequence([][]interface{}{{true, int64(1)}, {true, int64(2)}, {true, int64(3)}}); err != nil {
+		t.Error(err)
+	}
+	for _, expected := range [][][]interface{}{
+		{{true, int64(1)}, {true, int64(3)}, {true, int64(2)}},
+		{{true, int64(1)}, {true, int64(2)}},
+		{{true, int64(1)}, {true}, {true, int64(3)}},
+	} {
+		if err := fr.AssertArgSequence(expected); err == nil {
+			t.Errorf("Mismatching sequence %v should fail assertion", expected)
+		}
+	}
+	if err := Catcher.NewMock().AssertArgSequence(nil); err != errNotCaptured {
+		t.Errorf("Assertion without capture mismatches. Expected: [%v] , Got: [%v]", errNotCaptured, err)
+	}
+}
+
+func TestInvocations(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	fr := Catcher.Reset().NewMock().WithQuery("UPDATE users").CaptureArgs()
+
+	before := time.Now()
+	db.Exec("UPDATE users SET age = ? WHERE id = ?", 27, 1)
+	db.Exec("UPDATE users SET name = ?", sql.Named("name", "FirstLast"))
+
+	calls := fr.Invocations()
+	if len(calls) != 2 {
+		t.Fatalf("Invocations count mismatches. Expected: [%v] , Got: [%v]", 2, len(calls))
+	}
+	if calls[0].Query != "UPDATE users SET age = ? WHERE id = ?" {
+		t.Errorf("Query mismatches. Got: [%v]", calls[0].Query)
+	}
+	if len(calls[0].Args) != 2 || calls[0].Args[1].Ordinal != 2 || calls[0].Args[1].Value != int64(1) {
+		t.Errorf("Ordinal args mismatch. Got: [%v]", calls[0].Args)
+	}
+	if len(calls[1].Args) != 1 || calls[1].Args[0].Name != "name" || calls[1].Args[0].Value != "FirstLast" {
+		t.Errorf("Named args mismatch. Got: [%v]", calls[1].Args)
+	}
+	if calls[0].When.Before(before) || calls[1].When.Before(calls[0].When) {
+		t.Errorf("Invocation times are out of order. Got: [%v] and [%v]", calls[0].When, calls[1].When)
+	}
+
+	calls[0].Args[0].Value = "changed"
+	if fr.Invocations()[0].Args[0].Value != int64(27) {
+		t.Errorf("Returned invocations should not share args with the mock")
+	}
+}
+
+func TestAssertExpectations(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+
+	for _, tc := range []struct {
+		name  string
+		calls int
+		fails bool
+	}{
+		{"under", 1, true},
+		{"exact", 2, false},
+		{"over", 3, true},
+	} {
+		Catcher.Reset().NewMock().WithQuery("SELECT name FROM users").WithExpectedCalls(2)
+		Catcher.NewMock().WithQuery("DELETE FROM users").WithExpectedCalls(0)
+		for i := 0; i < tc.calls; i++ {
+			db.Query("SELECT name FROM users WHERE id = ?", i)
+		}
+		if err := Catcher.AssertExpectations(); (err != nil) != tc.fails {
+			t.Errorf("%s calls: expectations error mismatches. Expected failure: [%v] , Got: [%v]", tc.name, tc.fails, err)
+		}
+	}
+
+	db.Exec("DELETE FROM users")
+	if err := Catcher.AssertExpectations(); err == nil {
+		t.Errorf("Mock expected to be never called should fail after call")
+	}
+}
+
+func TestAssertTxBalanced(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset()
+
+	committed, _ := db.Begin()
+	committed.Commit()
+	rolledBack, _ := db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelSerializable})
+	rolledBack.Rollback()
+	if err := Catcher.AssertTxBalanced(); err != nil {
+		t.Errorf("Ended transactions should be balanced. Got: [%v]", err)
+	}
+
+	leaked, _ := db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelSerializable})
+	err := Catcher.AssertTxBalanced()
+	if err == nil || !strings.Contains(err.Error(), "#3 (Serializable)") {
+		t.Errorf("Leaked transaction should be reported. Got: [%v]", err)
+	}
+	if err := Catcher.Verify(); err == nil {
+		t.Errorf("Verify should report leaked transaction")
+	}
+	leaked.Rollback()
+}
+
+func TestVerify(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT name FROM users").WithExpectedCalls(1)
+	defer Catcher.AllowTables(nil)
+
+	db.Query("SELECT name FROM users")
+	if err := Catcher.Verify(); err != nil {
+		t.Errorf("Verify should pass. Got: [%v]", err)
+	}
+
+	db.Query("SELECT name FROM users")
+	db.Query("SELECT age FROM profiles")
+	Catcher.AllowTables([]string{"users", "profiles"})
+	db.Exec("DELETE FROM orders")
+	err := Catcher.Verify()
+	if err == nil {
+		t.Fatalf("Verify should fail")
+	}
+	for _, part := range []string{"expected to be called 1 times, called 2 times", `"SELECT age FROM profiles"`, "orders"} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("Verify error should contain [%v]. Got: [%v]", part, err)
+		}
+	}
+	if err := Catcher.AssertNoUnmatched(); err == nil || strings.Contains(err.Error(), "SELECT name") {
+		t.Errorf("Only unmatched queries should be reported. Got: [%v]", err)
+	}
+}
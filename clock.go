@@ -0,0 +1,31 @@
+package gomocket
+
+import (
+	"time"
+)
+
+// Clock is the source of time for response delays, connect delays and query history,
+// tests could set a fake one to MockCatcher.Clock to control time without sleeping
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is Clock of the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// clock returns Clock of the catcher or the real clock if it is not set
+func (mc *MockCatcher) clock() Clock {
+	if mc.Clock != nil {
+		return mc.Clock
+	}
+	return realClock{}
+}
@@ -4,18 +4,17 @@ import (
 	"context"
 	"database/sql/driver"
 	"errors"
-	"log"
-	"regexp"
 	"strings"
 	"sync"
 )
 
 // FakeConn implements connection
 type FakeConn struct {
-	db     *FakeDB
-	currTx *FakeTx // Transaction pointer
-	mu     sync.Mutex
-	bad    bool
+	db      *FakeDB
+	catcher *MockCatcher // Catcher serving queries of this connection
+	currTx  *FakeTx      // Transaction pointer
+	mu      sync.Mutex
+	bad     bool
 }
 
 func (c *FakeConn) isBad() bool {
@@ -27,17 +26,38 @@ func (c *FakeConn) Begin() (driver.Tx, error) {
 	if c.isBad() {
 		return nil, driver.ErrBadConn
 	}
+	if err := c.catcher.downError(); err != nil {
+		return nil, err
+	}
 	if c.currTx != nil {
 		return nil, errors.New("already in a transaction")
 	}
 	c.currTx = &FakeTx{c: c}
+	c.catcher.beginTx(c.currTx)
 	return c.currTx, nil
 }
 
-// Close terminates the db object
+// BeginTx starts and returns a new transaction with options, queries inside it could be matched
+// by their isolation level and the catcher records the last one
+func (c *FakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	tx, err := c.Begin()
+	if err != nil {
+		return nil, err
+	}
+	c.currTx.opts = opts
+	c.catcher.recordTx(opts)
+	return tx, nil
+}
+
+// Ping checks if the database is reachable, it fails only while the catcher simulates database down
+func (c *FakeConn) Ping(ctx context.Context) error {
+	return c.catcher.downError()
+}
+
+// Close terminates the db object, it returns ConnCloseError of the catcher if set
 func (c *FakeConn) Close() (err error) {
 	c.db = nil
-	return nil
+	return c.catcher.ConnCloseError
 }
 
 // Exec is deprecated
@@ -45,9 +65,9 @@ func (c *FakeConn) Exec(query string, args []driver.Value) (driver.Result, error
 	panic("ExecContext was not called.")
 }
 
-// ExecContext is optional to implement and it returns skip
+// ExecContext executes a query directly, without Prepare and Close of a statement
 func (c *FakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
-	return nil, driver.ErrSkip
+	return c.newStmt(query).ExecContext(ctx, args)
 }
 
 // Query is deprecated
@@ -55,33 +75,37 @@ func (c *FakeConn) Query(query string, args []driver.Value) (driver.Rows, error)
 	panic("QueryContext was not called.")
 }
 
-// QueryContext is optional
+// QueryContext executes a query directly, without Prepare and Close of a statement
 func (c *FakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
-	return nil, driver.ErrSkip
+	return c.newStmt(query).QueryContext(ctx, args)
 }
 
-// Prepare is optional
+// Prepare returns a prepared statement, bound to this connection.
+// database/sql uses PrepareContext, Prepare is called by wrappers of the connection
 func (c *FakeConn) Prepare(query string) (driver.Stmt, error) {
-	panic("use Prepare")
+	return c.PrepareContext(context.Background(), query)
 }
 
 // PrepareContext returns a prepared statement, bound to this connection.
 // context is for the preparation of the statement,
 // it must not store the context within the statement itself.
 func (c *FakeConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
-	var firstStmt = &FakeStmt{q: query, connection: c}
-	// Checking how many placeholders do we have
-	if strings.Contains(query, "$1") {
-		r, err := regexp.Compile(`[$]\d+`)
-		if err != nil {
-			log.Fatalf(`Cant't compile regexp with err [%v]`, err)
-		}
-		firstStmt.placeholders = len(strings.Split(r.ReplaceAllString(query, `$$$`), "$$")) - 1 // Postgres notation
-	} else {
-		firstStmt.placeholders = len(strings.Split(query, "?")) - 1 // Postgres notation
+	if err := c.catcher.downError(); err != nil {
+		return nil, err
 	}
+	c.catcher.countPrepare()
+	stmt := c.newStmt(query)
+	stmt.responses = c.catcher.prepareResponses(query)
+	stmt.maxUses = maxStmtUses(stmt.responses)
+	return stmt, nil
+}
+
+// newStmt parses query into a statement bound to this connection
+func (c *FakeConn) newStmt(query string) *FakeStmt {
+	var firstStmt = &FakeStmt{q: query, connection: c}
+	firstStmt.placeholders = countPlaceholders(query)
 
 	queryParts := strings.Split(query, " ") // By First statement define the query type
 	firstStmt.command = strings.ToUpper(queryParts[0])
-	return firstStmt, nil
+	return firstStmt
 }
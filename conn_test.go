@@ -0,0 +1,473 @@
+package gomocket
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDirectQueries(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+
+	t.Run("Query does not prepare statement", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery("SELECT name FROM users").WithReply([]map[string]interface{}{{"name": "FirstLast"}})
+		before := Catcher.PrepareCount()
+		var name string
+		if err := db.QueryRow("SELECT name FROM users WHERE age=?", 27).Scan(&name); err != nil {
+			t.Fatalf("Query failed [%v]", err)
+		}
+		if name != "FirstLast" {
+			t.Errorf("Name mismatches. Expected: [%v] , Got: [%v]", "FirstLast", name)
+		}
+		if count := Catcher.PrepareCount(); count != before {
+			t.Errorf("Prepare was called. Expected: [%v] , Got: [%v]", before, count)
+		}
+	})
+
+	t.Run("Exec does not prepare statement", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery("UPDATE users").WithRowsNum(3)
+		before := Catcher.PrepareCount()
+		res, err := db.Exec("UPDATE users SET age = ?", 27)
+		if err != nil {
+			t.Fatalf("Exec failed [%v]", err)
+		}
+		if affected, _ := res.RowsAffected(); affected != 3 {
+			t.Errorf("Rows affected mismatches. Expected: [%v] , Got: [%v]", 3, affected)
+		}
+		if count := Catcher.PrepareCount(); count != before {
+			t.Errorf("Prepare was called. Expected: [%v] , Got: [%v]", before, count)
+		}
+	})
+
+	t.Run("Prepare still counts statements", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery("SELECT name FROM users").WithReply([]map[string]interface{}{{"name": "FirstLast"}})
+		before := Catcher.PrepareCount()
+		stmt, err := db.PrepareContext(context.Background(), "SELECT name FROM users WHERE age=?")
+		if err != nil {
+			t.Fatalf("Prepare failed [%v]", err)
+		}
+		defer stmt.Close()
+		var name string
+		if err := stmt.QueryRow(27).Scan(&name); err != nil {
+			t.Fatalf("Query failed [%v]", err)
+		}
+		if count := Catcher.PrepareCount(); count != before+1 {
+			t.Errorf("Prepare count mismatches. Expected: [%v] , Got: [%v]", before+1, count)
+		}
+	})
+}
+
+func BenchmarkDirectQuery(b *testing.B) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT name FROM users").WithReply([]map[string]interface{}{{"name": "FirstLast"}})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var name string
+		db.QueryRow("SELECT name FROM users WHERE age=?", 27).Scan(&name)
+	}
+}
+
+func BenchmarkPreparedQuery(b *testing.B) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT name FROM users").WithReply([]map[string]interface{}{{"name": "FirstLast"}})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var name string
+		stmt, _ := db.Prepare("SELECT name FROM users WHERE age=?")
+		stmt.QueryRow(27).Scan(&name)
+		stmt.Close()
+	}
+}
+
+func TestSimulateDown(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT name FROM users").WithReply([]map[string]interface{}{{"name": "FirstLast"}})
+	defer Catcher.SimulateUp()
+
+	t.Run("Custom error", func(t *testing.T) {
+		downErr := errors.New("database is down")
+		Catcher.SimulateDown(downErr)
+		if _, err := db.Query("SELECT name FROM users"); err != downErr {
+			t.Errorf("Query error mismatches. Expected: [%v] , Got: [%v]", downErr, err)
+		}
+		if _, err := db.Exec("UPDATE users SET age = 1"); err != downErr {
+			t.Errorf("Exec error mismatches. Expected: [%v] , Got: [%v]", downErr, err)
+		}
+		if _, err := db.Begin(); err != downErr {
+			t.Errorf("Begin error mismatches. Expected: [%v] , Got: [%v]", downErr, err)
+		}
+		if err := db.Ping(); err != downErr {
+			t.Errorf("Ping error mismatches. Expected: [%v] , Got: [%v]", downErr, err)
+		}
+	})
+
+	t.Run("Bad connection by default", func(t *testing.T) {
+		Catcher.SimulateDown(nil)
+		if _, err := db.Query("SELECT name FROM users"); err != driver.ErrBadConn {
+			t.Errorf("Query error mismatches. Expected: [%v] , Got: [%v]", driver.ErrBadConn, err)
+		}
+		if err := db.Ping(); err != driver.ErrBadConn {
+			t.Errorf("Ping error mismatches. Expected: [%v] , Got: [%v]", driver.ErrBadConn, err)
+		}
+	})
+
+	t.Run("Back up", func(t *testing.T) {
+		Catcher.SimulateUp()
+		if err := db.Ping(); err != nil {
+			t.Fatalf("Ping failed [%v]", err)
+		}
+		var name string
+		if err := db.QueryRow("SELECT name FROM users").Scan(&name); err != nil {
+			t.Fatalf("Query failed [%v]", err)
+		}
+		if name != "FirstLast" {
+			t.Errorf("Name mismatches. Expected: [%v] , Got: [%v]", "FirstLast", name)
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("Begin failed [%v]", err)
+		}
+		tx.Rollback()
+	})
+}
+
+func TestOnConnect(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "on_connect")
+	defer db.Close()
+	defer func() { Catcher.OnConnect = nil }()
+
+	connectErr := errors.New("handshake failed")
+	connections := 0
+	Catcher.OnConnect = func() error {
+		connections++
+		if connections == 2 {
+			return connectErr
+		}
+		return nil
+	}
+
+	ctx := context.Background()
+	first, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("First connection failed [%v]", err)
+	}
+	defer first.Close()
+
+	// First connection is busy, so pool has to open second one
+	if _, err := db.Conn(ctx); err != connectErr {
+		t.Errorf("Second connection error mismatches. Expected: [%v] , Got: [%v]", connectErr, err)
+	}
+	if connections != 2 {
+		t.Errorf("OnConnect calls mismatch. Expected: [%v] , Got: [%v]", 2, connections)
+	}
+}
+
+func TestCloseErrors(t *testing.T) {
+	Catcher.Register()
+
+	t.Run("Statement close error", func(t *testing.T) {
+		db, _ := sql.Open(DriverName, "connection_string")
+		closeErr := errors.New("stmt close failed")
+		Catcher.Reset().NewMock().WithQuery("SELECT name FROM users").WithStmtCloseError(closeErr)
+		ctx := context.Background()
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatalf("Connection failed [%v]", err)
+		}
+		defer conn.Close()
+
+		stmt, err := conn.PrepareContext(ctx, "SELECT name FROM users")
+		if err != nil {
+			t.Fatalf("Prepare failed [%v]", err)
+		}
+		if err := stmt.Close(); err != closeErr {
+			t.Errorf("Close error mismatches. Expected: [%v] , Got: [%v]", closeErr, err)
+		}
+
+		stmt, _ = conn.PrepareContext(ctx, "SELECT id FROM goods")
+		if err := stmt.Close(); err != nil {
+			t.Errorf("Other statement should close without error. Got: [%v]", err)
+		}
+	})
+
+	t.Run("Connection close error", func(t *testing.T) {
+		closeErr := errors.New("conn close failed")
+		Catcher.ConnCloseError = closeErr
+		defer func() { Catcher.ConnCloseError = nil }()
+		db, _ := sql.Open(DriverName, "conn_close")
+		if err := db.Ping(); err != nil {
+			t.Fatalf("Ping failed [%v]", err)
+		}
+		if err := db.Close(); err != closeErr {
+			t.Errorf("Close error mismatches. Expected: [%v] , Got: [%v]", closeErr, err)
+		}
+	})
+}
+
+// countingConn wraps the built-in connection and counts calls
+type countingConn struct {
+	driver.Conn
+	prepares int
+	queries  int
+}
+
+func (c *countingConn) Prepare(query string) (driver.Stmt, error) {
+	c.prepares++
+	return c.Conn.Prepare(query)
+}
+
+func (c *countingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.queries++
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func TestConnFactory(t *testing.T) {
+	catcher := Register("conn_factory")
+	conn := &countingConn{Conn: catcher.NewConn()}
+	catcher.ConnFactory = func() driver.Conn {
+		return conn
+	}
+	catcher.Reset().NewMock().WithQuery("SELECT name FROM users").WithReply([]map[string]interface{}{{"name": "FirstLast"}})
+	db, _ := sql.Open(DriverName, "conn_factory")
+	defer db.Close()
+
+	var name string
+	if err := db.QueryRow("SELECT name FROM users").Scan(&name); err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	if name != "FirstLast" {
+		t.Errorf("Name mismatches. Expected: [%v] , Got: [%v]", "FirstLast", name)
+	}
+	stmt, err := db.Prepare("SELECT name FROM users")
+	if err != nil {
+		t.Fatalf("Prepare failed [%v]", err)
+	}
+	stmt.Close()
+
+	if conn.queries != 1 || conn.prepares != 1 {
+		t.Errorf("Calls mismatch. Expected: [1 1] , Got: [%v %v]", conn.queries, conn.prepares)
+	}
+}
+
+func TestConnectDelay(t *testing.T) {
+	catcher := Register("connect_delay")
+	catcher.ConnectDelay = time.Second
+	db, _ := sql.Open(DriverName, "connect_delay")
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := db.PingContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Ping error mismatches. Expected: [%v] , Got: [%v]", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed >= catcher.ConnectDelay {
+		t.Errorf("Connect did not respect context deadline. Took: [%v]", elapsed)
+	}
+
+	catcher.ConnectDelay = 10 * time.Millisecond
+	start = time.Now()
+	if err := db.PingContext(context.Background()); err != nil {
+		t.Fatalf("Ping failed [%v]", err)
+	}
+	if elapsed := time.Since(start); elapsed < catcher.ConnectDelay {
+		t.Errorf("Connect should wait ConnectDelay. Took: [%v]", elapsed)
+	}
+}
+
+func TestPassthrough(t *testing.T) {
+	// Any database/sql driver works as the real database, a named catcher stands in for it here
+	backend := Register("passthrough_backend")
+	backend.Reset().NewMock().WithQuery("SELECT name FROM users WHERE id = 2").WithArgs(int64(2)).
+		WithReply([]map[string]interface{}{{"name": "FromBackend"}})
+	backend.NewMock().WithQuery("UPDATE users").WithRowsNum(3)
+	real, _ := sql.Open(DriverName, "passthrough_backend")
+	defer real.Close()
+
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().Passthrough(real).NewMock().WithQuery("SELECT name FROM users WHERE id = 1").
+		WithReply([]map[string]interface{}{{"name": "FromMock"}})
+	defer Catcher.Passthrough(nil)
+
+	var name string
+	if err := db.QueryRow("SELECT name FROM users WHERE id = ?", 1).Scan(&name); err != nil || name != "FromMock" {
+		t.Errorf("Mocked query should be intercepted. Got: [%v] [%v]", name, err)
+	}
+	if err := db.QueryRow("SELECT name FROM users WHERE id = ?", 2).Scan(&name); err != nil || name != "FromBackend" {
+		t.Errorf("Unmocked query should reach real database. Got: [%v] [%v]", name, err)
+	}
+	res, err := db.Exec("UPDATE users SET name = ?", "x")
+	if err != nil {
+		t.Fatalf("Exec failed [%v]", err)
+	}
+	if affected, _ := res.RowsAffected(); affected != 3 {
+		t.Errorf("Rows affected mismatches. Expected: [%v] , Got: [%v]", 3, affected)
+	}
+	if backend.LastQuery() != "UPDATE users SET name = ?" {
+		t.Errorf("Forwarded query mismatches. Got: [%v]", backend.LastQuery())
+	}
+}
+
+func TestTransactionMatching(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("UPDATE accounts").WithinTx().WithRowsNum(2)
+	Catcher.NewMock().WithQuery("SELECT balance").OutsideTx().WithReply([]map[string]interface{}{{"balance": 10}})
+
+	res, _ := db.Exec("UPDATE accounts SET balance = 0")
+	if affected, _ := res.RowsAffected(); affected == 2 {
+		t.Errorf("WithinTx mock should not match query outside of transaction")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed [%v]", err)
+	}
+	res, _ = tx.Exec("UPDATE accounts SET balance = 0")
+	if affected, _ := res.RowsAffected(); affected != 2 {
+		t.Errorf("Rows affected in transaction mismatches. Expected: [%v] , Got: [%v]", 2, affected)
+	}
+	var balance int
+	if err := tx.QueryRow("SELECT balance FROM accounts").Scan(&balance); err != sql.ErrNoRows {
+		t.Errorf("OutsideTx mock should not match query inside transaction. Got: [%v] [%v]", balance, err)
+	}
+	tx.Commit()
+
+	if err := db.QueryRow("SELECT balance FROM accounts").Scan(&balance); err != nil || balance != 10 {
+		t.Errorf("OutsideTx mock should match after commit. Got: [%v] [%v]", balance, err)
+	}
+}
+
+func TestRequestIDMatching(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT name").WithRequestID("alice").WithReply([]map[string]interface{}{{"name": "Alice"}})
+	Catcher.NewMock().WithQuery("SELECT name").WithRequestID("bob").WithReply([]map[string]interface{}{{"name": "Bob"}})
+
+	for id, expected := range map[string]string{"alice": "Alice", "bob": "Bob"} {
+		var name string
+		ctx := ContextWithRequestID(context.Background(), id)
+		if err := db.QueryRowContext(ctx, "SELECT name FROM users").Scan(&name); err != nil || name != expected {
+			t.Errorf("Request %s: name mismatches. Expected: [%v] , Got: [%v] [%v]", id, expected, name, err)
+		}
+	}
+	var name string
+	if err := db.QueryRow("SELECT name FROM users").Scan(&name); err != sql.ErrNoRows {
+		t.Errorf("Query without request id should not match scoped mocks. Got: [%v] [%v]", name, err)
+	}
+}
+
+func TestIsolationMatching(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("UPDATE accounts").WithIsolation(sql.LevelSerializable).WithRowsNum(2)
+
+	tx, err := db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		t.Fatalf("BeginTx failed [%v]", err)
+	}
+	if Catcher.LastIsolation() != sql.LevelReadCommitted {
+		t.Errorf("Last isolation mismatches. Expected: [%v] , Got: [%v]", sql.LevelReadCommitted, Catcher.LastIsolation())
+	}
+	res, _ := tx.Exec("UPDATE accounts SET balance = 0")
+	if affected, _ := res.RowsAffected(); affected == 2 {
+		t.Errorf("Mock should not match transaction with other isolation level")
+	}
+	tx.Rollback()
+
+	tx, _ = db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelSerializable})
+	res, _ = tx.Exec("UPDATE accounts SET balance = 0")
+	if affected, _ := res.RowsAffected(); affected != 2 {
+		t.Errorf("Rows affected mismatches. Expected: [%v] , Got: [%v]", 2, affected)
+	}
+	tx.Commit()
+	if Catcher.LastIsolation() != sql.LevelSerializable {
+		t.Errorf("Last isolation mismatches. Expected: [%v] , Got: [%v]", sql.LevelSerializable, Catcher.LastIsolation())
+	}
+
+	res, _ = db.Exec("UPDATE accounts SET balance = 0")
+	if affected, _ := res.RowsAffected(); affected == 2 {
+		t.Errorf("Mock should not match query outside of transaction")
+	}
+}
+
+func TestReadOnlyTx(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT balance").WithinReadOnlyTx().WithReply([]map[string]interface{}{{"balance": int64(10)}})
+	Catcher.NewMock().WithQuery("UPDATE accounts").WithRowsNum(1)
+	readOnlyErr := errors.New("cannot execute UPDATE in a read-only transaction")
+	Catcher.ReadOnlyExecError = readOnlyErr
+	defer func() { Catcher.ReadOnlyExecError = nil }()
+
+	var balance int64
+	if err := db.QueryRow("SELECT balance FROM accounts").Scan(&balance); err != sql.ErrNoRows {
+		t.Errorf("Read-only mock should not match outside of transaction. Got: [%v] [%v]", balance, err)
+	}
+	if _, err := db.Exec("UPDATE accounts SET balance = 0"); err != nil {
+		t.Errorf("Exec outside of read-only transaction should succeed. Got: [%v]", err)
+	}
+
+	tx, err := db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("BeginTx failed [%v]", err)
+	}
+	defer tx.Rollback()
+	if err := tx.QueryRow("SELECT balance FROM accounts").Scan(&balance); err != nil || balance != 10 {
+		t.Errorf("Read-only mock should match inside read-only transaction. Got: [%v] [%v]", balance, err)
+	}
+	if _, err := tx.Exec("UPDATE accounts SET balance = 0"); err != readOnlyErr {
+		t.Errorf("Exec inside read-only transaction mismatches. Expected: [%v] , Got: [%v]", readOnlyErr, err)
+	}
+}
+
+func TestDefaultExecResult(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("INSERT INTO users")
+	Catcher.NewMock().WithQuery("INSERT INTO orders").WithID(7).WithRowsNum(2)
+	Catcher.NewMock().WithQuery("UPDATE users")
+	Catcher.DefaultRowsAffected, Catcher.DefaultLastInsertId = 5, 42
+	defer func() { Catcher.DefaultRowsAffected, Catcher.DefaultLastInsertId = 0, 0 }()
+
+	for _, tc := range []struct {
+		query        string
+		lastInsertID int64
+		rowsAffected int64
+	}{
+		{"INSERT INTO users (name) VALUES (?)", 42, 5},
+		{"INSERT INTO orders (total) VALUES (?)", 7, 2},
+		{"UPDATE users SET name = ?", 0, 5},
+	} {
+		res, err := db.Exec(tc.query, "value")
+		if err != nil {
+			t.Fatalf("Exec of %q failed [%v]", tc.query, err)
+		}
+		if rows, _ := res.RowsAffected(); rows != tc.rowsAffected {
+			t.Errorf("Rows affected of %q mismatches. Expected: [%v] , Got: [%v]", tc.query, tc.rowsAffected, rows)
+		}
+		if tc.lastInsertID == 0 {
+			continue
+		}
+		if id, _ := res.LastInsertId(); id != tc.lastInsertID {
+			t.Errorf("Last insert id of %q mismatches. Expected: [%v] , Got: [%v]", tc.query, tc.lastInsertID, id)
+		}
+	}
+
+	res, err := db.Exec("UPDATE orders SET total = ?", 1)
+	if err != nil {
+		t.Fatalf("Exec of unmocked query failed [%v]", err)
+	}
+	if rows, _ := res.RowsAffected(); rows != 0 {
+		t.Errorf("Defaults should not apply to unmatched queries. Got rows affected: [%v]", rows)
+	}
+}
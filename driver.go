@@ -1,6 +1,7 @@
 package gomocket
 
 import (
+	"context"
 	"database/sql/driver"
 	"log"
 	"sync"
@@ -8,6 +9,9 @@ import (
 
 var _ = log.Printf
 
+// fakeDriver is the FakeDriver registered as DriverName
+var fakeDriver = &FakeDriver{}
+
 // FakeDriver implements driver interface in sql package
 type FakeDriver struct {
 	mu         sync.Mutex // guards 3 following fields
@@ -45,7 +49,57 @@ func (t *table) columnIndex(name string) int {
 
 // Open returns a new connection to the database.
 func (d *FakeDriver) Open(database string) (driver.Conn, error) {
-	return &FakeConn{db: d.getDB(database)}, nil
+	return d.connect(context.Background(), database)
+}
+
+// OpenConnector returns connector for the database, which respects context while connecting
+func (d *FakeDriver) OpenConnector(database string) (driver.Connector, error) {
+	return &fakeConnector{driver: d, database: database}, nil
+}
+
+// connect opens a connection to the database served by the catcher bound to its name
+func (d *FakeDriver) connect(ctx context.Context, database string) (driver.Conn, error) {
+	return d.connectCatcher(ctx, database, catcherFor(database))
+}
+
+// connectCatcher waits ConnectDelay of the catcher and opens a connection served by it
+func (d *FakeDriver) connectCatcher(ctx context.Context, database string, catcher *MockCatcher) (driver.Conn, error) {
+	if catcher.ConnectDelay > 0 {
+		select {
+		case <-catcher.clock().After(catcher.ConnectDelay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if catcher.OnConnect != nil {
+		if err := catcher.OnConnect(); err != nil {
+			return nil, err
+		}
+	}
+	if catcher.ConnFactory != nil {
+		return catcher.ConnFactory(), nil
+	}
+	return &FakeConn{db: d.getDB(database), catcher: catcher}, nil
+}
+
+// fakeConnector implements driver.Connector for the database of FakeDriver
+type fakeConnector struct {
+	driver   *FakeDriver
+	database string
+	catcher  *MockCatcher // catcher bound directly, nil to look it up by database
+}
+
+// Connect returns a new connection to the database
+func (c *fakeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.catcher != nil {
+		return c.driver.connectCatcher(ctx, c.database, c.catcher)
+	}
+	return c.driver.connect(ctx, c.database)
+}
+
+// Driver returns the underlying FakeDriver
+func (c *fakeConnector) Driver() driver.Driver {
+	return c.driver
 }
 
 func (d *FakeDriver) getDB(name string) *FakeDB {
@@ -0,0 +1,413 @@
+package gomocket
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// catcherJSON is serializable state of the catcher
+type catcherJSON struct {
+	Logging              bool       `json:"logging,omitempty"`
+	PanicOnEmptyResponse bool       `json:"panicOnEmptyResponse,omitempty"`
+	StrictMatching       bool       `json:"strictMatching,omitempty"`
+	Dialect              Dialect    `json:"dialect,omitempty"`
+	ErrorOnAmbiguous     bool       `json:"errorOnAmbiguous,omitempty"`
+	DefaultRowsAffected  int64      `json:"defaultRowsAffected,omitempty"`
+	DefaultLastInsertId  int64      `json:"defaultLastInsertId,omitempty"`
+	Mocks                []mockJSON `json:"mocks"`
+}
+
+// mockJSON is serializable definition of FakeResponse, funcs and runtime state are left out
+type mockJSON struct {
+	Pattern        string                     `json:"pattern,omitempty"`
+	Patterns       []string                   `json:"patterns,omitempty"`
+	Forbidden      []string                   `json:"forbidden,omitempty"`
+	Regexp         string                     `json:"regexp,omitempty"`
+	Strict         bool                       `json:"strict,omitempty"`
+	Binds          bool                       `json:"binds,omitempty"`
+	Fingerprint    bool                       `json:"fingerprint,omitempty"`
+	Placeholders   bool                       `json:"placeholders,omitempty"`
+	InBatch        bool                       `json:"inBatch,omitempty"`
+	Args           []interface{}              `json:"args,omitempty"`
+	NamedArgs      map[string]interface{}     `json:"namedArgs,omitempty"`
+	ArgsHash       string                     `json:"argsHash,omitempty"`
+	FirstArg       interface{}                `json:"firstArg,omitempty"`
+	ByFirstArg     bool                       `json:"byFirstArg,omitempty"`
+	ByOrdinal      bool                       `json:"byOrdinal,omitempty"`
+	NumericLoose   bool                       `json:"numericLoose,omitempty"`
+	Response       []map[string]interface{}   `json:"response,omitempty"`
+	Table          []map[string]interface{}   `json:"table,omitempty"`
+	Pages          [][]map[string]interface{} `json:"pages,omitempty"`
+	ReplyData      [][]interface{}            `json:"replyData,omitempty"`
+	RoundRobin     [][]map[string]interface{} `json:"roundRobin,omitempty"`
+	FilterColumn   string                     `json:"filterColumn,omitempty"`
+	MaxRows        int                        `json:"maxRows,omitempty"`
+	Paginate       bool                       `json:"paginate,omitempty"`
+	Columns        []string                   `json:"columns,omitempty"`
+	ColumnTypes    []columnTypeJSON           `json:"columnTypes,omitempty"`
+	Validate       bool                       `json:"validate,omitempty"`
+	InferTypes     bool                       `json:"inferColumnTypes,omitempty"`
+	OrderBy        string                     `json:"orderBy,omitempty"`
+	OrderDesc      bool                       `json:"orderDesc,omitempty"`
+	FirstRowCols   bool                       `json:"columnsFromFirstRow,omitempty"`
+	Once           bool                       `json:"once,omitempty"`
+	Call           int                        `json:"call,omitempty"`
+	FallThrough    int                        `json:"fallThrough,omitempty"`
+	InTx           bool                       `json:"inTx,omitempty"`
+	NoTx           bool                       `json:"noTx,omitempty"`
+	Isolation      sql.IsolationLevel         `json:"isolation,omitempty"`
+	ByIsolation    bool                       `json:"byIsolation,omitempty"`
+	ReadOnly       bool                       `json:"readOnly,omitempty"`
+	RequestID      string                     `json:"requestId,omitempty"`
+	Priority       int                        `json:"priority,omitempty"`
+	ActiveFrom     int                        `json:"activeFrom,omitempty"`
+	ActiveUntil    int                        `json:"activeUntil,omitempty"`
+	Group          string                     `json:"group,omitempty"`
+	Meta           map[string]interface{}     `json:"meta,omitempty"`
+	Notices        []string                   `json:"notices,omitempty"`
+	Persistent     bool                       `json:"persistent,omitempty"`
+	Expect         bool                       `json:"expect,omitempty"`
+	ExpectedCalls  int                        `json:"expectedCalls,omitempty"`
+	RowsAffected   int64                      `json:"rowsAffected,omitempty"`
+	LastInsertID   int64                      `json:"lastInsertId,omitempty"`
+	Delay          time.Duration              `json:"delay,omitempty"`
+	Error          string                     `json:"error,omitempty"`
+	RowError       string                     `json:"rowError,omitempty"`
+	RowErrorAt     int                        `json:"rowErrorAt,omitempty"`
+	RowErrors      map[int]string             `json:"rowErrors,omitempty"`
+	ConvertErrors  map[string]string          `json:"convertErrors,omitempty"`
+	StmtCloseError string                     `json:"stmtCloseError,omitempty"`
+	MaxStmtUses    int                        `json:"maxStmtUses,omitempty"`
+	ReplyError     string                     `json:"replyError,omitempty"`
+	Capture        bool                       `json:"capture,omitempty"`
+}
+
+type columnTypeJSON struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+}
+
+// MarshalJSON serializes mock definitions and catcher flags, so failing scenario could be shared and
+// reproduced by json.Unmarshal into a catcher. Callbacks, encoders, hooks, other funcs, ArgTypes and
+// dependencies are skipped, scan types of ColumnTypes are derived from types again, errors keep only their messages,
+// RowErrors and ConvertErrors included.
+// Mocks with ArgumentMatcher args can not be serialized and fail with error
+func (mc *MockCatcher) MarshalJSON() ([]byte, error) {
+	mc.mu.Lock()
+	state := catcherJSON{
+		Logging:              mc.Logging,
+		PanicOnEmptyResponse: mc.PanicOnEmptyResponse,
+		StrictMatching:       mc.StrictMatching,
+		Dialect:              mc.Dialect,
+		ErrorOnAmbiguous:     mc.ErrorOnAmbiguous,
+		DefaultRowsAffected:  mc.DefaultRowsAffected,
+		DefaultLastInsertId:  mc.DefaultLastInsertId,
+		Mocks:                make([]mockJSON, 0, len(mc.Mocks)),
+	}
+	mocks := append([]*FakeResponse(nil), mc.Mocks...)
+	mc.mu.Unlock()
+
+	for _, fr := range mocks {
+		m, err := fr.toJSON()
+		if err != nil {
+			return nil, err
+		}
+		state.Mocks = append(state.Mocks, m)
+	}
+	return json.Marshal(state)
+}
+
+// UnmarshalJSON replaces mocks and flags of the catcher with ones serialized by MarshalJSON.
+// Whole numbers are loaded as int64 and other numbers as float64, like database/sql passes args
+func (mc *MockCatcher) UnmarshalJSON(data []byte) error {
+	var state catcherJSON
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&state); err != nil {
+		return fmt.Errorf("mock_catcher: can not load catcher: %v", err)
+	}
+
+	mocks := make([]*FakeResponse, 0, len(state.Mocks))
+	for _, m := range state.Mocks {
+		fr, err := m.toResponse()
+		if err != nil {
+			return err
+		}
+		mocks = append(mocks, fr)
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.Logging = state.Logging
+	mc.PanicOnEmptyResponse = state.PanicOnEmptyResponse
+	mc.StrictMatching = state.StrictMatching
+	mc.Dialect = state.Dialect
+	mc.ErrorOnAmbiguous = state.ErrorOnAmbiguous
+	mc.DefaultRowsAffected = state.DefaultRowsAffected
+	mc.DefaultLastInsertId = state.DefaultLastInsertId
+	mc.Mocks = mocks
+	return nil
+}
+
+func (fr *FakeResponse) toJSON() (mockJSON, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	for _, arg := range fr.Args {
+		if _, ok := arg.(ArgumentMatcher); ok {
+			return mockJSON{}, fmt.Errorf("mock_catcher: args of %q use ArgumentMatcher which can not be serialized", fr.Pattern)
+		}
+	}
+	if _, ok := fr.FirstArg.(ArgumentMatcher); ok {
+		return mockJSON{}, fmt.Errorf("mock_catcher: first arg of %q is ArgumentMatcher which can not be serialized", fr.Pattern)
+	}
+	for _, arg := range fr.NamedArgs {
+		if _, ok := arg.(ArgumentMatcher); ok {
+			return mockJSON{}, fmt.Errorf("mock_catcher: named args of %q use ArgumentMatcher which can not be serialized", fr.Pattern)
+		}
+	}
+	m := mockJSON{
+		Pattern:        fr.Pattern,
+		Patterns:       fr.Patterns,
+		Forbidden:      fr.Forbidden,
+		Regexp:         regexpString(fr.Regexp),
+		Strict:         fr.Strict,
+		Binds:          fr.Binds,
+		Fingerprint:    fr.Fingerprint,
+		Placeholders:   fr.Placeholders,
+		InBatch:        fr.InBatch,
+		Args:           fr.Args,
+		NamedArgs:      fr.NamedArgs,
+		ArgsHash:       fr.ArgsHash,
+		FirstArg:       fr.FirstArg,
+		ByFirstArg:     fr.ByFirstArg,
+		ByOrdinal:      fr.ByOrdinal,
+		NumericLoose:   fr.NumericLoose,
+		Response:       fr.Response,
+		Table:          fr.Table,
+		Pages:          fr.Pages,
+		RoundRobin:     fr.RoundRobin,
+		FilterColumn:   fr.FilterColumn,
+		MaxRows:        fr.MaxRows,
+		Paginate:       fr.Paginate,
+		Columns:        fr.Columns,
+		Validate:       fr.Validate,
+		InferTypes:     fr.InferTypes,
+		OrderBy:        fr.OrderBy,
+		OrderDesc:      fr.OrderDesc,
+		FirstRowCols:   fr.FirstRowCols,
+		Once:           fr.Once,
+		Call:           fr.Call,
+		FallThrough:    fr.FallThrough,
+		InTx:           fr.InTx,
+		NoTx:           fr.NoTx,
+		Isolation:      fr.Isolation,
+		ByIsolation:    fr.ByIsolation,
+		ReadOnly:       fr.ReadOnly,
+		RequestID:      fr.RequestID,
+		Priority:       fr.Priority,
+		ActiveFrom:     fr.ActiveFrom,
+		ActiveUntil:    fr.ActiveUntil,
+		Group:          fr.Group,
+		Meta:           fr.Meta,
+		Notices:        fr.Notices,
+		Persistent:     fr.Persistent,
+		Expect:         fr.Expect,
+		ExpectedCalls:  fr.ExpectedCalls,
+		RowsAffected:   fr.RowsAffected,
+		LastInsertID:   fr.LastInsertID,
+		Delay:          fr.Delay,
+		Error:          errorMessage(fr.Error),
+		RowError:       errorMessage(fr.RowError),
+		RowErrorAt:     fr.RowErrorAt,
+		StmtCloseError: errorMessage(fr.StmtCloseError),
+		MaxStmtUses:    fr.MaxStmtUses,
+		ReplyError:     errorMessage(fr.ReplyError),
+		Capture:        fr.Capture,
+	}
+	for _, meta := range fr.ColumnTypes {
+		m.ColumnTypes = append(m.ColumnTypes, columnTypeJSON{Name: meta.Name, Type: meta.Type})
+	}
+	if fr.RowErrors != nil {
+		m.RowErrors = make(map[int]string, len(fr.RowErrors))
+		for i, err := range fr.RowErrors {
+			m.RowErrors[i] = errorMessage(err)
+		}
+	}
+	if fr.ConvertErrors != nil {
+		m.ConvertErrors = make(map[string]string, len(fr.ConvertErrors))
+		for col, err := range fr.ConvertErrors {
+			m.ConvertErrors[col] = errorMessage(err)
+		}
+	}
+	for _, values := range fr.ReplyData {
+		row := make([]interface{}, len(values))
+		for i, v := range values {
+			row[i] = v
+		}
+		m.ReplyData = append(m.ReplyData, row)
+	}
+	return m, nil
+}
+
+func (m mockJSON) toResponse() (*FakeResponse, error) {
+	fr := &FakeResponse{
+		Pattern:        m.Pattern,
+		Patterns:       m.Patterns,
+		Forbidden:      m.Forbidden,
+		Strict:         m.Strict,
+		Binds:          m.Binds,
+		Fingerprint:    m.Fingerprint,
+		Placeholders:   m.Placeholders,
+		InBatch:        m.InBatch,
+		ArgsHash:       m.ArgsHash,
+		FirstArg:       jsonValue(m.FirstArg),
+		ByFirstArg:     m.ByFirstArg,
+		ByOrdinal:      m.ByOrdinal,
+		NumericLoose:   m.NumericLoose,
+		Response:       make([]map[string]interface{}, 0, len(m.Response)),
+		FilterColumn:   m.FilterColumn,
+		MaxRows:        m.MaxRows,
+		Paginate:       m.Paginate,
+		Columns:        m.Columns,
+		Validate:       m.Validate,
+		InferTypes:     m.InferTypes,
+		OrderBy:        m.OrderBy,
+		OrderDesc:      m.OrderDesc,
+		FirstRowCols:   m.FirstRowCols,
+		Once:           m.Once,
+		Call:           m.Call,
+		FallThrough:    m.FallThrough,
+		InTx:           m.InTx,
+		NoTx:           m.NoTx,
+		Isolation:      m.Isolation,
+		ByIsolation:    m.ByIsolation,
+		ReadOnly:       m.ReadOnly,
+		RequestID:      m.RequestID,
+		Priority:       m.Priority,
+		ActiveFrom:     m.ActiveFrom,
+		ActiveUntil:    m.ActiveUntil,
+		Group:          m.Group,
+		Notices:        m.Notices,
+		Persistent:     m.Persistent,
+		Expect:         m.Expect,
+		ExpectedCalls:  m.ExpectedCalls,
+		RowsAffected:   m.RowsAffected,
+		LastInsertID:   m.LastInsertID,
+		Delay:          m.Delay,
+		Error:          messageError(m.Error),
+		RowError:       messageError(m.RowError),
+		RowErrorAt:     m.RowErrorAt,
+		StmtCloseError: messageError(m.StmtCloseError),
+		MaxStmtUses:    m.MaxStmtUses,
+		ReplyError:     messageError(m.ReplyError),
+		Capture:        m.Capture,
+		Exceptions:     &Exceptions{},
+	}
+	for _, arg := range m.Args {
+		fr.Args = append(fr.Args, jsonValue(arg))
+	}
+	if m.NamedArgs != nil {
+		fr.NamedArgs = make(map[string]interface{}, len(m.NamedArgs))
+		for k, v := range m.NamedArgs {
+			fr.NamedArgs[k] = jsonValue(v)
+		}
+	}
+	if m.RowErrors != nil {
+		fr.RowErrors = make(map[int]error, len(m.RowErrors))
+		for i, msg := range m.RowErrors {
+			fr.RowErrors[i] = messageError(msg)
+		}
+	}
+	if m.ConvertErrors != nil {
+		fr.ConvertErrors = make(map[string]error, len(m.ConvertErrors))
+		for col, msg := range m.ConvertErrors {
+			fr.ConvertErrors[col] = messageError(msg)
+		}
+	}
+	fr.Response = append(fr.Response, jsonRows(m.Response)...)
+	fr.Table = jsonRows(m.Table)
+	if m.Meta != nil {
+		fr.Meta = make(map[string]interface{}, len(m.Meta))
+		for k, v := range m.Meta {
+			fr.Meta[k] = jsonValue(v)
+		}
+	}
+	for _, page := range m.Pages {
+		fr.Pages = append(fr.Pages, jsonRows(page))
+	}
+	for _, reply := range m.RoundRobin {
+		fr.RoundRobin = append(fr.RoundRobin, jsonRows(reply))
+	}
+	for _, values := range m.ReplyData {
+		loaded := make([]driver.Value, len(values))
+		for i, v := range values {
+			loaded[i] = jsonValue(v)
+		}
+		fr.ReplyData = append(fr.ReplyData, loaded)
+	}
+	for _, ct := range m.ColumnTypes {
+		fr.ColumnTypes = append(fr.ColumnTypes, ColumnMeta{Name: ct.Name, Type: ct.Type})
+	}
+	if m.Regexp != "" {
+		re, err := regexp.Compile(m.Regexp)
+		if err != nil {
+			return nil, fmt.Errorf("mock_catcher: can not load query regexp of mock: %v", err)
+		}
+		fr.Regexp, fr.regexpLiteral = re, requiredLiteral(re)
+	}
+	return fr, nil
+}
+
+func regexpString(re *regexp.Regexp) string {
+	if re == nil {
+		return ""
+	}
+	return re.String()
+}
+
+func jsonRows(rows []map[string]interface{}) []map[string]interface{} {
+	if rows == nil {
+		return nil
+	}
+	loaded := make([]map[string]interface{}, len(rows))
+	for i, r := range rows {
+		loaded[i] = make(map[string]interface{}, len(r))
+		for k, v := range r {
+			loaded[i][k] = jsonValue(v)
+		}
+	}
+	return loaded
+}
+
+// jsonValue converts decoded number to int64 if it is whole or to float64 otherwise
+func jsonValue(v interface{}) interface{} {
+	n, ok := v.(json.Number)
+	if !ok {
+		return v
+	}
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	f, _ := n.Float64()
+	return f
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func messageError(msg string) error {
+	if msg == "" {
+		return nil
+	}
+	return errors.New(msg)
+}
@@ -0,0 +1,75 @@
+package gomocket
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+)
+
+// Passthrough forwards queries no mock matches to a real database instead of returning empty response,
+// so only some queries of integration tests are mocked. Forwarded queries run on db connection pool
+// outside of any transaction begun on mocked connection, so they neither see its uncommitted changes
+// nor are rolled back with it. Call with nil to stop forwarding
+func (mc *MockCatcher) Passthrough(db *sql.DB) *MockCatcher {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.passthrough = db
+	return mc
+}
+
+// passthroughArgs converts driver args back to the form accepted by sql.DB
+func passthroughArgs(args []driver.NamedValue) []interface{} {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			values[i] = sql.Named(arg.Name, arg.Value)
+			continue
+		}
+		values[i] = arg.Value
+	}
+	return values
+}
+
+// passthroughQuery runs query on real database and reads all its rows into cursor
+func passthroughQuery(ctx context.Context, db *sql.DB, query string, args []driver.NamedValue) (*RowsCursor, error) {
+	rows, err := db.QueryContext(ctx, query, passthroughArgs(args)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	colTypes := make([]string, len(cols))
+	if types, err := rows.ColumnTypes(); err == nil {
+		for i, ct := range types {
+			colTypes[i] = ct.DatabaseTypeName()
+		}
+	}
+
+	var result []*row
+	for rows.Next() {
+		values := make([]interface{}, len(cols))
+		dest := make([]interface{}, len(cols))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		result = append(result, &row{cols: values})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &RowsCursor{
+		posRow:  -1,
+		rows:    [][]*row{result},
+		cols:    cols,
+		colType: [][]string{colTypes},
+		errPos:  -1,
+	}, nil
+}
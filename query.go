@@ -0,0 +1,170 @@
+package gomocket
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+)
+
+var (
+	stringLiteralRe = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numberLiteralRe = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	dollarParamRe   = regexp.MustCompile(`\$\d+`)
+	namedParamRe    = regexp.MustCompile(`(^|[^:\w]):[A-Za-z_]\w*`)
+	tableRe         = regexp.MustCompile(`(?i)\b(?:FROM|JOIN|INTO|UPDATE|TABLE)\s+([^\s,;()]+)`)
+	limitRe         = regexp.MustCompile(`(?i)\bLIMIT\s+(\d+)(?:\s*,\s*(\d+)|\s+OFFSET\s+(\d+))?\s*;?\s*$`)
+)
+
+// Fingerprint replaces string and numeric literals of the query with "?" placeholders,
+// so queries which differ only by inlined values have the same fingerprint
+func Fingerprint(query string) string {
+	query = stringLiteralRe.ReplaceAllString(query, "?")
+	return numberLiteralRe.ReplaceAllString(query, "?")
+}
+
+// normalizePlaceholders rewrites Postgres $1 and named :id placeholders to "?",
+// Postgres ::type casts are kept as they are
+func normalizePlaceholders(query string) string {
+	query = dollarParamRe.ReplaceAllString(query, "?")
+	return namedParamRe.ReplaceAllString(query, "${1}?")
+}
+
+// countPlaceholders returns how many Postgres $1 or "?" placeholders the query has
+func countPlaceholders(query string) int {
+	if strings.Contains(query, "$1") {
+		return len(strings.Split(dollarParamRe.ReplaceAllString(query, `$$$`), "$$")) - 1 // Postgres notation
+	}
+	return len(strings.Split(query, "?")) - 1
+}
+
+// queryTables extracts names of tables used by the query. It is best-effort: names following
+// FROM, JOIN, INTO, UPDATE and TABLE are taken, quotes and schema prefix are stripped
+func queryTables(query string) []string {
+	var tables []string
+	for _, match := range tableRe.FindAllStringSubmatch(query, -1) {
+		name := match[1]
+		if i := strings.LastIndexByte(name, '.'); i >= 0 {
+			name = name[i+1:]
+		}
+		name = strings.Trim(name, "`\"[]")
+		if name != "" {
+			tables = append(tables, strings.ToLower(name))
+		}
+	}
+	return tables
+}
+
+// queryWindow extracts trailing `LIMIT n`, `LIMIT n OFFSET m` or MySQL `LIMIT m, n` of the query.
+// It returns false if the query has no such clause
+func queryWindow(query string) (limit, offset int, ok bool) {
+	match := limitRe.FindStringSubmatch(query)
+	if match == nil {
+		return 0, 0, false
+	}
+	limit, _ = strconv.Atoi(match[1])
+	switch {
+	case match[2] != "":
+		offset = limit
+		limit, _ = strconv.Atoi(match[2])
+	case match[3] != "":
+		offset, _ = strconv.Atoi(match[3])
+	}
+	return limit, offset, true
+}
+
+// splitStatements splits batch of statements on semicolons outside of quoted strings and identifiers,
+// statements are trimmed and empty ones are dropped
+func splitStatements(query string) []string {
+	var statements []string
+	var quote rune
+	start := 0
+	for i, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0 // doubled quote escaping a quote closes and reopens the string
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == ';':
+			if statement := strings.TrimSpace(query[start:i]); statement != "" {
+				statements = append(statements, statement)
+			}
+			start = i + 1
+		}
+	}
+	if statement := strings.TrimSpace(query[start:]); statement != "" {
+		statements = append(statements, statement)
+	}
+	return statements
+}
+
+// Dialect selects normalization applied by the catcher to queries and patterns before they are compared.
+// Quotes of identifiers are removed, placeholders are rewritten to "?" and text outside string literals
+// is lower-cased, as keywords and unquoted identifiers are case insensitive
+type Dialect string
+
+// Dialects supported by MockCatcher.Dialect, the empty one compares queries as they are
+const (
+	DialectPostgres Dialect = "postgres" // $1 placeholders and "quoted" identifiers
+	DialectMySQL    Dialect = "mysql"    // `quoted` identifiers
+	DialectSQLite   Dialect = "sqlite"   // ?1, :name, @name and $name placeholders, "quoted", `quoted` and [quoted] identifiers
+)
+
+var (
+	sqliteParamRe      = regexp.MustCompile(`\?\d+|[:@$][A-Za-z_]\w*`)
+	sqliteQuoteStrip   = strings.NewReplacer(`"`, "", "`", "", "[", "", "]", "")
+	postgresQuoteStrip = strings.NewReplacer(`"`, "")
+	mysqlQuoteStrip    = strings.NewReplacer("`", "")
+)
+
+// normalize rewrites the query by the dialect rules, string literals are kept as they are
+func (d Dialect) normalize(query string) string {
+	var rewrite func(string) string
+	switch d {
+	case DialectPostgres:
+		rewrite = func(part string) string {
+			return postgresQuoteStrip.Replace(dollarParamRe.ReplaceAllString(part, "?"))
+		}
+	case DialectMySQL:
+		rewrite = mysqlQuoteStrip.Replace
+	case DialectSQLite:
+		rewrite = func(part string) string {
+			return sqliteQuoteStrip.Replace(sqliteParamRe.ReplaceAllString(part, "?"))
+		}
+	default:
+		return query
+	}
+
+	var normalized strings.Builder
+	last := 0
+	for _, literal := range stringLiteralRe.FindAllStringIndex(query, -1) {
+		normalized.WriteString(strings.ToLower(rewrite(query[last:literal[0]])))
+		normalized.WriteString(query[literal[0]:literal[1]])
+		last = literal[1]
+	}
+	normalized.WriteString(strings.ToLower(rewrite(query[last:])))
+	return normalized.String()
+}
+
+// requiredLiteral returns the longest case sensitive literal every match of the regular expression contains,
+// so queries without it could be skipped before running the expression. Empty means no such literal is known
+func requiredLiteral(re *regexp.Regexp) string {
+	parsed, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return ""
+	}
+	parsed = parsed.Simplify()
+	parts := []*syntax.Regexp{parsed}
+	if parsed.Op == syntax.OpConcat {
+		parts = parsed.Sub
+	}
+	var literal string
+	for _, part := range parts {
+		if part.Op == syntax.OpLiteral && part.Flags&syntax.FoldCase == 0 && len(string(part.Rune)) > len(literal) {
+			literal = string(part.Rune)
+		}
+	}
+	return literal
+}
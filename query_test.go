@@ -0,0 +1,97 @@
+package gomocket
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestQueryTables(t *testing.T) {
+	cases := map[string][]string{
+		`SELECT * FROM users WHERE id = 1`:                                   {"users"},
+		`SELECT * FROM "public"."users" u JOIN orders o ON o.user_id = u.id`: {"users", "orders"},
+		"INSERT INTO `logs` (msg) VALUES (?)":                                {"logs"},
+		`UPDATE Accounts SET balance = 0`:                                    {"accounts"},
+		`SELECT * FROM (SELECT 1) t`:                                         nil,
+	}
+	for query, expected := range cases {
+		if tables := queryTables(query); !reflect.DeepEqual(tables, expected) {
+			t.Errorf("Tables of [%v] mismatch. Expected: [%v] , Got: [%v]", query, expected, tables)
+		}
+	}
+}
+
+func TestNormalizePlaceholders(t *testing.T) {
+	cases := map[string]string{
+		`SELECT * FROM users WHERE id = ?`:                `SELECT * FROM users WHERE id = ?`,
+		`SELECT * FROM users WHERE id = $1 AND age > $12`: `SELECT * FROM users WHERE id = ? AND age > ?`,
+		`SELECT * FROM users WHERE id = :id AND (a=:a)`:   `SELECT * FROM users WHERE id = ? AND (a=?)`,
+		`SELECT id::text FROM users WHERE id = :id`:       `SELECT id::text FROM users WHERE id = ?`,
+	}
+	for query, expected := range cases {
+		if normalized := normalizePlaceholders(query); normalized != expected {
+			t.Errorf("Normalized query mismatches. Expected: [%v] , Got: [%v]", expected, normalized)
+		}
+	}
+}
+
+func TestQueryWindow(t *testing.T) {
+	cases := map[string][3]int{
+		`SELECT * FROM users LIMIT 10`:            {10, 0, 1},
+		`SELECT * FROM users limit 10 offset 20;`: {10, 20, 1},
+		`SELECT * FROM users LIMIT 20, 10`:        {10, 20, 1},
+		`SELECT * FROM users`:                     {0, 0, 0},
+		`SELECT * FROM (SELECT 1 LIMIT 1) t`:      {0, 0, 0},
+	}
+	for query, expected := range cases {
+		limit, offset, ok := queryWindow(query)
+		if got := [3]int{limit, offset, map[bool]int{true: 1}[ok]}; got != expected {
+			t.Errorf("Window of [%v] mismatches. Expected: [%v] , Got: [%v]", query, expected, got)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	cases := map[string][]string{
+		`CREATE TABLE users (id INT); INSERT INTO users VALUES (1);`: {`CREATE TABLE users (id INT)`, `INSERT INTO users VALUES (1)`},
+		`INSERT INTO notes VALUES ('a;b', 'it''s; fine')`:            {`INSERT INTO notes VALUES ('a;b', 'it''s; fine')`},
+		`SELECT ";" FROM t; ; SELECT 1`:                              {`SELECT ";" FROM t`, `SELECT 1`},
+	}
+	for query, expected := range cases {
+		if got := splitStatements(query); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Statements of [%v] mismatch. Expected: [%v] , Got: [%v]", query, expected, got)
+		}
+	}
+}
+
+func TestDialectNormalize(t *testing.T) {
+	cases := []struct {
+		dialect  Dialect
+		query    string
+		expected string
+	}{
+		{DialectPostgres, `SELECT "Name" FROM "users" WHERE id = $1 AND note = 'Say "Hi"'`, `select name from users where id = ? and note = 'Say "Hi"'`},
+		{DialectMySQL, "SELECT `Name` FROM `users` WHERE id = ?", `select name from users where id = ?`},
+		{DialectSQLite, `SELECT [Name] FROM "users" WHERE id = ?1 AND org = :org`, `select name from users where id = ? and org = ?`},
+		{"", `SELECT "Name" FROM users`, `SELECT "Name" FROM users`},
+	}
+	for _, c := range cases {
+		if got := c.dialect.normalize(c.query); got != c.expected {
+			t.Errorf("Normalized %v query mismatches. Expected: [%v] , Got: [%v]", c.dialect, c.expected, got)
+		}
+	}
+}
+
+func TestRequiredLiteral(t *testing.T) {
+	cases := map[string]string{
+		`^SELECT \* FROM users WHERE id = \d+$`: "SELECT * FROM users WHERE id = ",
+		`FROM (users|guests) WHERE`:             " WHERE",
+		`(?i)select name`:                       "",
+		`users|guests`:                          "",
+	}
+	for expr, expected := range cases {
+		if got := requiredLiteral(regexp.MustCompile(expr)); got != expected {
+			t.Errorf("Required literal of [%v] mismatches. Expected: [%q] , Got: [%q]", expr, expected, got)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package gomocket
+
+import (
+	"sync"
+)
+
+var (
+	catchersMu sync.Mutex
+	catchers   = make(map[string]*MockCatcher)
+)
+
+// Register returns catcher registered by name, creating it on first call, and registers FakeDriver.
+// Connections opened with the name as data source are bound to this catcher instead of the global Catcher
+//
+//	db, err := sql.Open(mocket.DriverName, "billing")
+func Register(name string) *MockCatcher {
+	Catcher.Register()
+	catchersMu.Lock()
+	defer catchersMu.Unlock()
+	mc, ok := catchers[name]
+	if !ok {
+		mc = &MockCatcher{}
+		catchers[name] = mc
+	}
+	return mc
+}
+
+// Get returns catcher registered by name or nil if there is no such catcher
+func Get(name string) *MockCatcher {
+	catchersMu.Lock()
+	defer catchersMu.Unlock()
+	return catchers[name]
+}
+
+// catcherFor returns catcher registered for data source name or the global Catcher
+func catcherFor(dsn string) *MockCatcher {
+	if mc := Get(dsn); mc != nil {
+		return mc
+	}
+	return Catcher
+}
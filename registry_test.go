@@ -0,0 +1,100 @@
+package gomocket
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestNamedCatchers(t *testing.T) {
+	billing := Register("billing")
+	users := Register("users")
+
+	if billing == users || billing == Catcher {
+		t.Fatalf("Named catchers should be distinct")
+	}
+	if Get("billing") != billing || Get("users") != users {
+		t.Errorf("Get should return registered catchers")
+	}
+	if Register("billing") != billing {
+		t.Errorf("Second Register should return existing catcher")
+	}
+	if Get("unknown") != nil {
+		t.Errorf("Get of unknown catcher should return nil")
+	}
+
+	billing.Reset().NewMock().WithQuery("SELECT name").WithReply([]map[string]interface{}{{"name": "billing"}})
+	users.Reset().NewMock().WithQuery("SELECT name").WithReply([]map[string]interface{}{{"name": "users"}})
+	Catcher.Reset()
+
+	for dsn, expected := range map[string]string{"billing": "billing", "users": "users"} {
+		db, _ := sql.Open(DriverName, dsn)
+		var name string
+		if err := db.QueryRow("SELECT name FROM accounts").Scan(&name); err != nil {
+			t.Fatalf("Query on %v failed [%v]", dsn, err)
+		}
+		if name != expected {
+			t.Errorf("Name mismatches. Expected: [%v] , Got: [%v]", expected, name)
+		}
+		db.Close()
+	}
+	if len(Catcher.Queries()) != 0 {
+		t.Errorf("Global Catcher should not see queries of named catchers")
+	}
+}
+
+func TestConnector(t *testing.T) {
+	mc := &MockCatcher{}
+	mc.NewMock().WithQuery("SELECT name").WithReply([]map[string]interface{}{{"name": "connector"}})
+	Catcher.Reset()
+
+	connector := mc.Connector()
+	if _, ok := connector.Driver().(*FakeDriver); !ok {
+		t.Fatalf("Connector driver mismatches. Expected: [*FakeDriver] , Got: [%T]", connector.Driver())
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	var name string
+	if err := db.QueryRow("SELECT name FROM accounts").Scan(&name); err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	if name != "connector" {
+		t.Errorf("Name mismatches. Expected: [%v] , Got: [%v]", "connector", name)
+	}
+	if len(mc.Queries()) != 1 || len(Catcher.Queries()) != 0 {
+		t.Errorf("Query should be caught by the catcher of connector only")
+	}
+}
+
+func TestRegisterBuiltMock(t *testing.T) {
+	users := Mock().WithQuery("SELECT name").WithReply([]map[string]interface{}{{"name": "FirstLast"}}).OneTime()
+	other := &MockCatcher{}
+	Catcher.Reset()
+	Catcher.Register(users)
+	other.Register(users)
+
+	for _, db := range []*sql.DB{sql.OpenDB(Catcher.Connector()), sql.OpenDB(other.Connector())} {
+		var name string
+		if err := db.QueryRow("SELECT name FROM users").Scan(&name); err != nil || name != "FirstLast" {
+			t.Errorf("Registered mock should serve the query. Got: [%v] [%v]", name, err)
+		}
+		db.Close()
+	}
+	if users.Triggered || len(Catcher.Mocks) != 1 || len(other.Mocks) != 1 {
+		t.Errorf("Catchers should serve own copies of the built mock")
+	}
+
+	insert := Mock().WithQuery("INSERT INTO users")
+	selectUser := Mock().WithQuery("SELECT name").WithReply([]map[string]interface{}{{"name": "FirstLast"}}).DependsOn(insert)
+	Catcher.Reset()
+	Catcher.Register(insert, selectUser)
+	db := sql.OpenDB(Catcher.Connector())
+	defer db.Close()
+	var name string
+	if err := db.QueryRow("SELECT name FROM users").Scan(&name); err != sql.ErrNoRows {
+		t.Errorf("Dependent mock should not match before its dependency. Got: [%v] [%v]", name, err)
+	}
+	db.Exec("INSERT INTO users (name) VALUES (?)", "FirstLast")
+	if err := db.QueryRow("SELECT name FROM users").Scan(&name); err != nil || name != "FirstLast" {
+		t.Errorf("Dependent mock should match after its registered dependency. Got: [%v] [%v]", name, err)
+	}
+}
@@ -0,0 +1,139 @@
+package gomocket
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// replayEntry is a reply recorded for a query, keyed by replayKey
+type replayEntry struct {
+	Key          string                   `json:"key"`
+	Query        string                   `json:"query"`
+	Columns      []string                 `json:"columns,omitempty"`
+	Rows         []map[string]interface{} `json:"rows,omitempty"`
+	RowsAffected int64                    `json:"rowsAffected,omitempty"`
+	LastInsertID int64                    `json:"lastInsertId,omitempty"`
+}
+
+// replayKey identifies query by its text and HashArgs of args. Whitespace is collapsed and text outside
+// string literals is lower-cased, inlined values are kept so queries differing by them are told apart
+func replayKey(query string, args []driver.NamedValue) string {
+	var key strings.Builder
+	last := 0
+	for _, literal := range stringLiteralRe.FindAllStringIndex(query, -1) {
+		key.WriteString(strings.ToLower(strings.Join(strings.Fields(query[last:literal[0]]), " ")))
+		key.WriteString(query[literal[0]:literal[1]])
+		last = literal[1]
+	}
+	key.WriteString(strings.ToLower(strings.Join(strings.Fields(query[last:]), " ")))
+	return key.String() + "\x00" + HashArgs(args)
+}
+
+// recordReply keeps reply served for the query in its history record to be exported by ExportReplay
+func (mc *MockCatcher) recordReply(call *queryCall, columns []string, rows []map[string]interface{}, res driver.Result) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if call.record == nil {
+		return
+	}
+	entry := &replayEntry{
+		Key:     replayKey(call.record.Query, call.record.Args),
+		Query:   call.record.Query,
+		Columns: append([]string(nil), columns...),
+		Rows:    replayRows(rows),
+	}
+	if res != nil {
+		entry.RowsAffected, _ = res.RowsAffected()
+		entry.LastInsertID, _ = res.LastInsertId()
+	}
+	call.record.reply = entry
+}
+
+// replayRows copies rows to be recorded, lazy values are recorded as NULL as they are computed only when emitted
+func replayRows(rows []map[string]interface{}) []map[string]interface{} {
+	recorded := cloneRows(rows)
+	for _, record := range recorded {
+		for k, v := range record {
+			if _, ok := v.(func() driver.Value); ok {
+				record[k] = nil
+			}
+		}
+	}
+	return recorded
+}
+
+// ExportReplay writes replies served since last Reset as JSON replay table, so later runs could serve them
+// by LoadReplay without mocks or real database. Replies of mocks and of queries forwarded by Passthrough
+// are recorded, so running once against a real database captures golden data. The last reply wins
+// for queries with the same text and args
+func (mc *MockCatcher) ExportReplay(w io.Writer) error {
+	mc.mu.Lock()
+	var entries []*replayEntry
+	index := make(map[string]int)
+	for _, record := range mc.history {
+		if record.reply == nil {
+			continue
+		}
+		if i, ok := index[record.reply.Key]; ok {
+			entries[i] = record.reply
+			continue
+		}
+		index[record.reply.Key] = len(entries)
+		entries = append(entries, record.reply)
+	}
+	mc.mu.Unlock()
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+// LoadReplay loads replay table written by ExportReplay. Queries with recorded text and args
+// are served from the table before mocks are checked. Whole numbers are loaded as int64,
+// the first Exec command word decides between rows affected and insert id as for mocks
+func (mc *MockCatcher) LoadReplay(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var entries []*replayEntry
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&entries); err != nil {
+		return fmt.Errorf("mock_catcher: can not load replay: %v", err)
+	}
+	replay := make(map[string]*replayEntry, len(entries))
+	for _, entry := range entries {
+		entry.Rows = jsonRows(entry.Rows)
+		replay[entry.Key] = entry
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.replay = replay
+	return nil
+}
+
+// replayResponse returns response for the query from loaded replay table, mc.mu has to be held
+func (mc *MockCatcher) replayResponse(query string, args []driver.NamedValue) *FakeResponse {
+	entry, ok := mc.replay[replayKey(query, args)]
+	if !ok {
+		return nil
+	}
+	response := cloneRows(entry.Rows)
+	if response == nil {
+		response = make([]map[string]interface{}, 0)
+	}
+	return &FakeResponse{
+		Pattern:      entry.Query,
+		Columns:      append([]string(nil), entry.Columns...),
+		Response:     response,
+		RowsAffected: entry.RowsAffected,
+		LastInsertID: entry.LastInsertID,
+		Exceptions:   &Exceptions{},
+	}
+}
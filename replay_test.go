@@ -0,0 +1,73 @@
+package gomocket
+
+import (
+	"bytes"
+	"database/sql"
+	"testing"
+)
+
+func TestReplayRoundTrip(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.ResetAll().NewMock().WithQuery("SELECT name, age FROM users").WithArgs(int64(1)).
+		WithReply([]map[string]interface{}{{"name": "FirstLast", "age": int64(30)}}).WithColumns("name", "age")
+	Catcher.NewMock().WithQuery("UPDATE users").WithRowsNum(3)
+
+	var name string
+	var age int64
+	db.QueryRow("SELECT name, age FROM users WHERE id = ?", 1).Scan(&name, &age)
+	db.Exec("UPDATE users SET age = ? WHERE id = ?", 31, 1)
+
+	var buf bytes.Buffer
+	if err := Catcher.ExportReplay(&buf); err != nil {
+		t.Fatalf("ExportReplay failed [%v]", err)
+	}
+	Catcher.ResetAll()
+	if err := Catcher.LoadReplay(&buf); err != nil {
+		t.Fatalf("LoadReplay failed [%v]", err)
+	}
+	defer Catcher.ResetAll()
+
+	name, age = "", 0
+	if err := db.QueryRow("SELECT name, age FROM users WHERE id = ?", 1).Scan(&name, &age); err != nil || name != "FirstLast" || age != 30 {
+		t.Errorf("Replayed query mismatches. Got: [%v] [%v] [%v]", name, age, err)
+	}
+	res, err := db.Exec("UPDATE users SET age = ? WHERE id = ?", 31, 1)
+	if err != nil {
+		t.Fatalf("Replayed exec failed [%v]", err)
+	}
+	if affected, _ := res.RowsAffected(); affected != 3 {
+		t.Errorf("Replayed rows affected mismatches. Expected: [%v] , Got: [%v]", 3, affected)
+	}
+	if err := db.QueryRow("SELECT name, age FROM users WHERE id = ?", 2).Scan(&name, &age); err != sql.ErrNoRows {
+		t.Errorf("Query with other args should not be replayed. Got: [%v]", err)
+	}
+}
+
+func TestReplayInlinedValues(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.ResetAll().NewMock().WithQuery("SELECT name FROM users WHERE id = 1").WithReply([]map[string]interface{}{{"name": "one"}})
+	Catcher.NewMock().WithQuery("SELECT name FROM users WHERE id = 2").WithReply([]map[string]interface{}{{"name": "two"}})
+
+	var name string
+	db.QueryRow("SELECT name FROM users WHERE id = 1").Scan(&name)
+	db.QueryRow("SELECT name FROM users WHERE id = 2").Scan(&name)
+
+	var buf bytes.Buffer
+	if err := Catcher.ExportReplay(&buf); err != nil {
+		t.Fatalf("ExportReplay failed [%v]", err)
+	}
+	Catcher.ResetAll()
+	if err := Catcher.LoadReplay(&buf); err != nil {
+		t.Fatalf("LoadReplay failed [%v]", err)
+	}
+	defer Catcher.ResetAll()
+
+	if err := db.QueryRow("SELECT name FROM users WHERE id = 1").Scan(&name); err != nil || name != "one" {
+		t.Errorf("Replayed query with inlined value mismatches. Expected: [%v] , Got: [%v] [%v]", "one", name, err)
+	}
+	if err := db.QueryRow("select name  from users\n\tWHERE id = 2").Scan(&name); err != nil || name != "two" {
+		t.Errorf("Replay should ignore whitespace and case. Expected: [%v] , Got: [%v] [%v]", "two", name, err)
+	}
+}
@@ -1,13 +1,23 @@
 package gomocket
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
 	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
 )
 
 const (
@@ -15,194 +25,2124 @@ const (
 	DriverName = "MOCK_FAKE_DRIVER"
 )
 
+// ErrQuotaExceeded is returned by queries over QueryQuota unless QuotaError is set
+var ErrQuotaExceeded = errors.New("mock_catcher: query quota exceeded")
+
+// ErrStmtExpired is returned by prepared statements used more times than WithMaxStmtUses allows
+var ErrStmtExpired = errors.New("mock_catcher: prepared statement expired, prepare it again")
+
 // Catcher is global instance of Catcher used for attaching all mocks to connection
 var Catcher *MockCatcher
 
 // MockCatcher is global entity to save all mocks aka FakeResponses
 type MockCatcher struct {
-	Mocks                []*FakeResponse // Slice of all mocks
-	Logging              bool            // Do we need to log what we catching?
-	PanicOnEmptyResponse bool            // If not response matches - do we need to panic?
+	Mocks                []*FakeResponse     // Slice of all mocks
+	Logging              bool                // Do we need to log what we catching?
+	PanicOnEmptyResponse bool                // If not response matches - do we need to panic?
+	StrictMatching       bool                // Queries no response matches fail with NoMatchError
+	ErrorOnAmbiguous     bool                // Queries several equally specific mocks match fail with AmbiguousMatchError
+	OnConnect            func() error        // Called on every new connection, returned error fails the connection
+	ConnectDelay         time.Duration       // How long opening a connection takes, interrupted by context cancellation
+	ConnCloseError       error               // Returned by Close of every connection
+	ReadOnlyExecError    error               // Returned by Exec inside read-only transaction instead of serving it
+	QueryQuota           int                 // How many queries are served until Reset or ResetState, 0 means unlimited
+	QuotaError           error               // Returned by queries over QueryQuota, ErrQuotaExceeded if nil
+	ConnFactory          func() driver.Conn  // Creates connections instead of the built-in one, see NewConn
+	RewriteQuery         func(string) string // Normalizes every query before it is compared with mocks
+	Clock                Clock               // Time source of delays and history, real time if nil
+	Dialect              Dialect             // Normalizes queries and patterns before they are compared
+	DefaultRowsAffected  int64               // Rows affected of Exec mocks leaving it at zero
+	DefaultLastInsertId  int64               // Insert id of INSERT mocks leaving it at zero, random if zero too
 	mu                   sync.Mutex
+	prepareCount         int            // How many statements were prepared
+	history              []*QueryRecord // Queries caught since last Reset
+	down                 bool           // Database is simulated to be down
+	downErr              error          // Error returned while database is down
+	interceptors         []func(next Handler) Handler
+	rnd                  *rand.Rand              // Source of all randomized behaviors, see Seed
+	allowedTables        map[string]bool         // Tables queries may use, nil means any
+	tableErrors          []error                 // Queries which used not allowed tables
+	passthrough          *sql.DB                 // Database serving queries no mock matches, see Passthrough
+	frozen               bool                    // Registering mocks panics, see Freeze
+	lastIsolation        sql.IsolationLevel      // Isolation level of the last begun transaction
+	quotaUsed            int64                   // Queries counted against QueryQuota, accessed atomically
+	replay               map[string]*replayEntry // Replies served before mocks are checked, see LoadReplay
+	transactions         []*FakeTx               // Transactions begun since last Reset, see AssertTxBalanced
+}
+
+// NoMatchError is returned by queries no mock matches while StrictMatching is on
+type NoMatchError struct {
+	Query string
+	Args  []driver.NamedValue
+}
+
+func (e *NoMatchError) Error() string {
+	return fmt.Sprintf("mock_catcher: no mock matches query %q with %d args", e.Query, len(e.Args))
+}
+
+// AmbiguousMatchError is returned by queries several mocks of the same priority match while ErrorOnAmbiguous is on.
+// Mocks limited by OnCall, OneTime or FallThroughAfter win over unlimited ones instead of being ambiguous
+type AmbiguousMatchError struct {
+	Query string
+	Mocks []*FakeResponse
+}
+
+func (e *AmbiguousMatchError) Error() string {
+	patterns := make([]string, len(e.Mocks))
+	for i, fr := range e.Mocks {
+		patterns[i] = strconv.Quote(fr.Pattern)
+	}
+	return fmt.Sprintf("mock_catcher: %d mocks of the same priority match query %q: %s", len(e.Mocks), e.Query, strings.Join(patterns, ", "))
+}
+
+// MetaCallbackFunc is callback of the mock receiving query, args and Meta of the mock, see WithMetaCallback
+type MetaCallbackFunc func(query string, args []driver.NamedValue, meta map[string]interface{})
+
+// Handler processes query with args and returns response holding rows, result and error for it
+type Handler func(query string, args []driver.NamedValue) *FakeResponse
+
+// ResponderFunc produces rows, result values and error of a single query execution, see WithResponder
+type ResponderFunc func(query string, args []driver.NamedValue) (rows []map[string]interface{}, rowsAffected, lastInsertID int64, err error)
+
+// QueryRecord keeps a query caught by FindResponse
+type QueryRecord struct {
+	Query    string                 // Query text as it was matched
+	Args     []driver.NamedValue    // Args of the query
+	Duration time.Duration          // How long the driver served the query, including WithDelay
+	Meta     map[string]interface{} // Meta of the mock matched the query, see WithMeta
+	Notices  []string               // Notices emitted by the mock matched the query, see WithNotices
+	Matched  bool                   // Query was served by a mock or replay table
+	reply    *replayEntry           // Reply served for the query, see ExportReplay
+}
+
+// queryCall holds state of a single query execution passed from the driver to findResponse
+type queryCall struct {
+	prepared     []*FakeResponse // Mocks bound to the statement at Prepare
+	record       *QueryRecord    // History record of the query, set by findResponse
+	tx           *FakeTx         // Transaction of the connection the query runs on, nil outside of transaction
+	requestID    string          // Request id found in the query context, see ContextWithRequestID
+	placeholders int             // How many placeholders the statement text has
+	caught       int             // How many queries the catcher caught before this one, set by findResponse
+}
+
+// isPrepared checks whether the mock was bound to the statement at Prepare
+func (call *queryCall) isPrepared(fr *FakeResponse) bool {
+	for _, prepared := range call.prepared {
+		if prepared == fr {
+			return true
+		}
+	}
+	return false
+}
+
+// requestIDKey is the context key of request id set by ContextWithRequestID
+type requestIDKey struct{}
+
+// ContextWithRequestID returns context carrying request id. Queries run with it match only mocks
+// without request id or with the same one set by WithRequestID, so concurrent handlers could share a catcher
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// requestIDFrom returns request id of the context or empty string
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func (mc *MockCatcher) SetLogging(l bool) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.Logging = l
+}
+
+// PrepareCount returns how many statements were prepared through the driver.
+// Direct db.Query and db.Exec calls do not prepare statements
+func (mc *MockCatcher) PrepareCount() int {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.prepareCount
+}
+
+func (mc *MockCatcher) countPrepare() {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.prepareCount++
+}
+
+// SimulateDown makes every Query, Exec, Prepare, Begin and Ping fail with err until SimulateUp is called.
+// With nil err driver.ErrBadConn is returned, so database/sql retries the call on new connections before giving up
+func (mc *MockCatcher) SimulateDown(err error) *MockCatcher {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if err == nil {
+		err = driver.ErrBadConn
+	}
+	mc.down = true
+	mc.downErr = err
+	return mc
+}
+
+// SimulateUp brings database back after SimulateDown
+func (mc *MockCatcher) SimulateUp() *MockCatcher {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.down = false
+	mc.downErr = nil
+	return mc
+}
+
+// downError returns error to fail with while database is simulated to be down
+func (mc *MockCatcher) downError() error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if !mc.down {
+		return nil
+	}
+	return mc.downErr
+}
+
+// String dumps all mocks as a table with pattern, args, reply rows count, triggered count and flags
+func (mc *MockCatcher) String() string {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tPATTERN\tARGS\tROWS\tTRIGGERED\tFLAGS")
+	for i, fr := range mc.Mocks {
+		fr.mu.Lock()
+		args := "*"
+		if fr.Args != nil {
+			args = fmt.Sprintf("%v", fr.Args)
+		} else if fr.NamedArgs != nil {
+			args = fmt.Sprintf("%v", fr.NamedArgs)
+		}
+		pattern := strconv.Quote(fr.Pattern)
+		if len(fr.Patterns) > 0 {
+			pattern = fmt.Sprintf("%q", fr.Patterns)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\t%s\n", i, pattern, args, len(fr.Response), fr.triggered, strings.Join(fr.flags(), ","))
+		fr.mu.Unlock()
+	}
+	w.Flush()
+	return b.String()
+}
+
+// MatchResult tells whether the mock matches a query and why, see Candidates
+type MatchResult struct {
+	Mock    *FakeResponse
+	Matched bool
+	Reason  string
+}
+
+// Candidates checks the query with args against every mock in the order they are checked,
+// reporting the first failed requirement of each, to explain why no mock or an unexpected one matched.
+// Requirements of the execution like transaction or request id, and Call counting are not checked
+func (mc *MockCatcher) Candidates(query string, args []driver.NamedValue) []MatchResult {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.RewriteQuery != nil {
+		query = mc.RewriteQuery(query)
+	}
+	mc.sortMocks()
+	results := make([]MatchResult, len(mc.Mocks))
+	first := -1
+	for i, fr := range mc.Mocks {
+		result := MatchResult{Mock: fr}
+		switch {
+		case fr.isExhausted():
+			result.Reason = "exhausted by OneTime or FallThroughAfter"
+		case !fr.isDependencyMet():
+			result.Reason = "dependency was not triggered yet"
+		case !fr.isQueryMatch(query, mc.Dialect):
+			result.Reason = "query does not match pattern"
+		case !fr.isArgsMatch(args):
+			result.Reason = "args do not match"
+		case first >= 0:
+			result.Matched = true
+			result.Reason = fmt.Sprintf("matches, but mock %d is checked first", first)
+		default:
+			result.Matched = true
+			result.Reason = "matches"
+			first = i
+		}
+		results[i] = result
+	}
+	return results
+}
+
+// Seed makes all randomized behaviors, like generated insert IDs, deterministic.
+// Without seeding the source is seeded by current time
+func (mc *MockCatcher) Seed(seed int64) *MockCatcher {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.rnd = rand.New(rand.NewSource(seed))
+	return mc
+}
+
+// randInt63 returns next random number of the catcher source
+func (mc *MockCatcher) randInt63() int64 {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.rnd == nil {
+		mc.rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return mc.rnd.Int63()
+}
+
+// NewConn returns the built-in connection bound to the catcher,
+// so connections created by ConnFactory can delegate to it
+func (mc *MockCatcher) NewConn() driver.Conn {
+	return &FakeConn{db: &FakeDB{}, catcher: mc}
+}
+
+// Register safely register FakeDriver and attaches copies of mocks built by Mock,
+// so one built mock could be registered in several catchers or tests with its own state.
+// Dependencies between mocks registered together point to their copies
+func (mc *MockCatcher) Register(mocks ...*FakeResponse) {
+	if len(mocks) > 0 {
+		mc.Attach(cloneMocks(mocks))
+	}
+	driversList := sql.Drivers()
+	for _, name := range driversList {
+		if name == DriverName {
+			return
+		}
+	}
+	sql.Register(DriverName, fakeDriver)
+}
+
+// Connector returns connector serving connections by the catcher, so it could be wired by sql.OpenDB without DSN
+//
+//	db := sql.OpenDB(mocket.Catcher.Connector())
+func (mc *MockCatcher) Connector() driver.Connector {
+	return &fakeConnector{driver: fakeDriver, catcher: mc}
+}
+
+// Attach several mocks to MockCather. Could be useful to attach mocks from some factories of mocks
+func (mc *MockCatcher) Attach(fr []*FakeResponse) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.checkFrozen()
+	mc.Mocks = append(mc.Mocks, fr...)
+}
+
+// AttachSorted attaches mocks keeping Mocks ordered by Priority, higher first and in order of registration
+// for equal priorities. The batch is sorted once and merged into already ordered mocks, so attaching
+// thousands of mocks does not sort the whole set again
+func (mc *MockCatcher) AttachSorted(frs []*FakeResponse) {
+	batch := append([]*FakeResponse(nil), frs...)
+	sort.SliceStable(batch, func(i, j int) bool { return batch[i].priority() > batch[j].priority() })
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.checkFrozen()
+	mc.sortMocks()
+	merged := make([]*FakeResponse, 0, len(mc.Mocks)+len(batch))
+	i, j := 0, 0
+	for i < len(mc.Mocks) && j < len(batch) {
+		if batch[j].priority() > mc.Mocks[i].priority() {
+			merged = append(merged, batch[j])
+			j++
+			continue
+		}
+		merged = append(merged, mc.Mocks[i])
+		i++
+	}
+	merged = append(merged, mc.Mocks[i:]...)
+	mc.Mocks = append(merged, batch[j:]...)
+}
+
+// sortMocks restores order of Mocks by Priority, broken by Attach, NewMock or WithPriority
+// since the last query. Ordered mocks are only checked, mc.mu has to be held
+func (mc *MockCatcher) sortMocks() {
+	for i := 1; i < len(mc.Mocks); i++ {
+		if mc.Mocks[i].priority() > mc.Mocks[i-1].priority() {
+			sort.SliceStable(mc.Mocks, func(a, b int) bool { return mc.Mocks[a].priority() > mc.Mocks[b].priority() })
+			return
+		}
+	}
+}
+
+// Freeze locks the set of mocks, registering mocks by NewMock or Attach panics until Unfreeze.
+// It catches mocks accidentally registered while queries already run, e.g. from goroutines
+func (mc *MockCatcher) Freeze() *MockCatcher {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.frozen = true
+	return mc
+}
+
+// Unfreeze allows registering mocks again after Freeze
+func (mc *MockCatcher) Unfreeze() *MockCatcher {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.frozen = false
+	return mc
+}
+
+// checkFrozen panics if mocks are frozen, mc.mu has to be held
+func (mc *MockCatcher) checkFrozen() {
+	if mc.frozen {
+		panic("mock_catcher: mocks are frozen, call Unfreeze before registering new ones")
+	}
+}
+
+// FindResponse finds suitable response by provided
+func (mc *MockCatcher) FindResponse(query string, args []driver.NamedValue) *FakeResponse {
+	return mc.findResponse(query, args, &queryCall{placeholders: countPlaceholders(query)})
+}
+
+// findResponse finds response like FindResponse. Mocks prepared for the statement are matched by args only,
+// as their patterns already matched the statement text at Prepare
+func (mc *MockCatcher) findResponse(query string, args []driver.NamedValue, call *queryCall) *FakeResponse {
+	var exhausted *FakeResponse
+	defer func() { // OnExhausted hook runs after the catcher is unlocked, so it could use it
+		if exhausted != nil {
+			exhausted.ExhaustHook(exhausted)
+		}
+	}()
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.RewriteQuery != nil {
+		query = mc.RewriteQuery(query)
+	}
+	if mc.Logging {
+		log.Printf("mock_catcher: check query: %s", query)
+	}
+	call.record = &QueryRecord{Query: query, Args: args}
+	call.caught = len(mc.history)
+	mc.history = append(mc.history, call.record)
+
+	if err := mc.checkTables(query); err != nil {
+		mc.tableErrors = append(mc.tableErrors, err)
+		return &FakeResponse{Error: err, Exceptions: &Exceptions{}}
+	}
+
+	if resp := mc.replayResponse(query, args); resp != nil {
+		call.record.Matched = true
+		return resp
+	}
+
+	mc.sortMocks()
+	var matched *FakeResponse
+	var ambiguous []*FakeResponse // Mocks of the same priority as matched one which would respond too
+	for _, resp := range mc.Mocks {
+		if !resp.isMatch(query, args, mc.Dialect) && (!call.isPrepared(resp) || !resp.isPreparedMatch(args)) {
+			continue
+		}
+		if !resp.isCallMatch(call) {
+			continue
+		}
+		// Every eligible mock has to see the call, otherwise OnCall counters drift
+		if !resp.nextCall() {
+			continue
+		}
+		if matched == nil {
+			matched = resp
+		} else if mc.ErrorOnAmbiguous && resp.priority() == matched.priority() {
+			// Mocks limited by OnCall, OneTime or FallThroughAfter are more specific than unlimited ones
+			switch limited := resp.isLimited(); {
+			case limited == matched.isLimited():
+				ambiguous = append(ambiguous, resp)
+			case limited:
+				matched, ambiguous = resp, nil
+			}
+		}
+	}
+
+	if len(ambiguous) > 0 {
+		return &FakeResponse{Error: &AmbiguousMatchError{Query: query, Mocks: append([]*FakeResponse{matched}, ambiguous...)}, Exceptions: &Exceptions{}}
+	}
+
+	if matched != nil {
+		if matched.markTriggered() {
+			exhausted = matched
+		}
+		matched.capture(query, args, mc.clock().Now())
+		call.record.Meta, call.record.Notices = matched.meta(), matched.notices()
+		call.record.Matched = true
+		return matched
+	}
+
+	if mc.passthrough != nil {
+		return &FakeResponse{Exceptions: &Exceptions{}, passthrough: mc.passthrough}
+	}
+
+	if mc.PanicOnEmptyResponse {
+		panic(fmt.Sprintf("No responses matches query %s ", query))
+	}
+
+	if mc.StrictMatching {
+		return &FakeResponse{Error: &NoMatchError{Query: query, Args: args}, Exceptions: &Exceptions{}}
+	}
+
+	// Let's have always dummy version of response
+	return &FakeResponse{
+		Response:   make([]map[string]interface{}, 0),
+		Exceptions: &Exceptions{},
+	}
+}
+
+// AllowTables restricts tables queries may use. Queries using any other table fail with error,
+// which is also recorded to be checked by TableErrors. Table names are extracted from queries
+// best-effort and compared case-insensitively. Call with nil to allow any table again
+func (mc *MockCatcher) AllowTables(tables []string) *MockCatcher {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.tableErrors = nil
+	if tables == nil {
+		mc.allowedTables = nil
+		return mc
+	}
+	mc.allowedTables = make(map[string]bool, len(tables))
+	for _, table := range tables {
+		mc.allowedTables[strings.ToLower(table)] = true
+	}
+	return mc
+}
+
+// TableErrors returns errors of queries which used tables not allowed by AllowTables
+func (mc *MockCatcher) TableErrors() []error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return append([]error(nil), mc.tableErrors...)
+}
+
+// checkTables returns error if query uses table not allowed by AllowTables, mc.mu has to be held
+func (mc *MockCatcher) checkTables(query string) error {
+	if mc.allowedTables == nil {
+		return nil
+	}
+	for _, table := range queryTables(query) {
+		if !mc.allowedTables[table] {
+			return fmt.Errorf("mock_catcher: query uses not allowed table %q: %s", table, query)
+		}
+	}
+	return nil
+}
+
+// Queries returns texts of all queries caught since last Reset in order of execution
+func (mc *MockCatcher) Queries() []string {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	queries := make([]string, len(mc.history))
+	for i, record := range mc.history {
+		queries[i] = record.Query
+	}
+	return queries
+}
+
+// LastQuery returns text of the last caught query or empty string if nothing was caught since last Reset
+func (mc *MockCatcher) LastQuery() string {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if len(mc.history) == 0 {
+		return ""
+	}
+	return mc.history[len(mc.history)-1].Query
+}
+
+// History returns records of all queries caught since last Reset in order of execution
+func (mc *MockCatcher) History() []QueryRecord {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	history := make([]QueryRecord, len(mc.history))
+	for i, record := range mc.history {
+		history[i] = *record
+	}
+	return history
+}
+
+// LastIsolation returns isolation level of the last transaction begun on connections of the catcher
+func (mc *MockCatcher) LastIsolation() sql.IsolationLevel {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.lastIsolation
+}
+
+func (mc *MockCatcher) recordTx(opts driver.TxOptions) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.lastIsolation = sql.IsolationLevel(opts.Isolation)
+}
+
+// beginTx tracks transaction begun on connection of the catcher until it is ended by endTx
+func (mc *MockCatcher) beginTx(tx *FakeTx) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.transactions = append(mc.transactions, tx)
+}
+
+// endTx marks transaction committed or rolled back
+func (mc *MockCatcher) endTx(tx *FakeTx) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	tx.ended = true
+}
+
+// Notices returns notices emitted by queries since last Reset in order, simulating out-of-band messages
+// like Postgres NOTICE
+func (mc *MockCatcher) Notices() []string {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	var notices []string
+	for _, record := range mc.history {
+		notices = append(notices, record.Notices...)
+	}
+	return notices
+}
+
+// SlowestQuery returns text and duration of the longest query since last Reset
+func (mc *MockCatcher) SlowestQuery() (string, time.Duration) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	var slowest *QueryRecord
+	for _, record := range mc.history {
+		if slowest == nil || record.Duration > slowest.Duration {
+			slowest = record
+		}
+	}
+	if slowest == nil {
+		return "", 0
+	}
+	return slowest.Query, slowest.Duration
+}
+
+// finish records duration of the query served by the driver
+func (mc *MockCatcher) finish(call *queryCall, start time.Time) {
+	if call.record == nil { // Interceptor did not call FindResponse
+		return
+	}
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	call.record.Duration = mc.clock().Now().Sub(start)
+}
+
+// Use wraps query handling with interceptor, e.g. to rewrite queries, trace them or transform responses.
+// FindResponse is the last handler in the chain, interceptors added first are called first
+func (mc *MockCatcher) Use(interceptor func(next Handler) Handler) *MockCatcher {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.interceptors = append(mc.interceptors, interceptor)
+	return mc
+}
+
+// handle passes query through interceptors to FindResponse
+func (mc *MockCatcher) handle(query string, args []driver.NamedValue, call *queryCall) *FakeResponse {
+	mc.mu.Lock()
+	interceptors := mc.interceptors
+	mc.mu.Unlock()
+
+	handler := Handler(func(query string, args []driver.NamedValue) *FakeResponse {
+		return mc.findResponse(query, args, call)
+	})
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		handler = interceptors[i](handler)
+	}
+	return handler(query, args)
+}
+
+// prepareResponses returns mocks matching statement text to bind them to the statement,
+// executions pick one of them by args
+func (mc *MockCatcher) prepareResponses(query string) []*FakeResponse {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.RewriteQuery != nil {
+		query = mc.RewriteQuery(query)
+	}
+	var responses []*FakeResponse
+	for _, fr := range mc.Mocks {
+		if !fr.isExhausted() && fr.isQueryMatch(query, mc.Dialect) {
+			responses = append(responses, fr)
+		}
+	}
+	return responses
+}
+
+// maxStmtUses returns use limit of the first mock bound to the statement which has it
+func maxStmtUses(responses []*FakeResponse) int {
+	for _, fr := range responses {
+		fr.mu.Lock()
+		n := fr.MaxStmtUses
+		fr.mu.Unlock()
+		if n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// stmtCloseError returns close error of the first mock matching statement query
+func (mc *MockCatcher) stmtCloseError(query string) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.RewriteQuery != nil {
+		query = mc.RewriteQuery(query)
+	}
+	for _, fr := range mc.Mocks {
+		fr.mu.Lock()
+		err := fr.StmtCloseError
+		fr.mu.Unlock()
+		if err != nil && fr.isQueryMatch(query, mc.Dialect) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Mock creates new FakeResponse without attaching it to any catcher, see Register
+//
+//	users := mocket.Mock().WithQuery("SELECT * FROM users").WithReply(rows)
+//	mocket.Catcher.Register(users)
+func Mock() *FakeResponse {
+	return &FakeResponse{Exceptions: &Exceptions{}, Response: make([]map[string]interface{}, 0)}
+}
+
+// NewMock creates new FakeResponse and return for chains of attachments
+func (mc *MockCatcher) NewMock() *FakeResponse {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.checkFrozen()
+	fr := Mock()
+	mc.Mocks = append(mc.Mocks, fr)
+	return fr
+}
+
+// GetMock returns the first mock registered with given Pattern or nil if there is none
+func (mc *MockCatcher) GetMock(pattern string) *FakeResponse {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	for _, resp := range mc.Mocks {
+		resp.mu.Lock()
+		registered := resp.Pattern
+		resp.mu.Unlock()
+		if registered == pattern {
+			return resp
+		}
+	}
+	return nil
+}
+
+// Reset removes all Mocks except persistent ones to start process again,
+// trigger counts and captured calls of persistent mocks are cleared as by ResetState
+func (mc *MockCatcher) Reset() *MockCatcher {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mocks := make([]*FakeResponse, 0)
+	for _, fr := range mc.Mocks {
+		if fr.isPersistent() {
+			fr.resetState()
+			mocks = append(mocks, fr)
+		}
+	}
+	mc.Mocks = mocks
+	mc.history = nil
+	mc.tableErrors = nil
+	mc.transactions = nil
+	atomic.StoreInt64(&mc.quotaUsed, 0)
+	return mc
+}
+
+// ResetState clears runtime state while keeping mocks: query history, used quota and trigger counts
+// and captured calls of every mock
+func (mc *MockCatcher) ResetState() *MockCatcher {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.history = nil
+	mc.tableErrors = nil
+	mc.transactions = nil
+	atomic.StoreInt64(&mc.quotaUsed, 0)
+	for _, fr := range mc.Mocks {
+		fr.resetState()
+	}
+	return mc
+}
+
+// useQuota counts query against QueryQuota and returns error if it is exceeded
+func (mc *MockCatcher) useQuota() error {
+	if mc.QueryQuota <= 0 {
+		return nil
+	}
+	if atomic.AddInt64(&mc.quotaUsed, 1) <= int64(mc.QueryQuota) {
+		return nil
+	}
+	if mc.QuotaError != nil {
+		return mc.QuotaError
+	}
+	return ErrQuotaExceeded
+}
+
+// RemainingQuota returns how many queries could run until QueryQuota is exceeded, -1 if there is no quota
+func (mc *MockCatcher) RemainingQuota() int {
+	if mc.QueryQuota <= 0 {
+		return -1
+	}
+	if remaining := int64(mc.QueryQuota) - atomic.LoadInt64(&mc.quotaUsed); remaining > 0 {
+		return int(remaining)
+	}
+	return 0
+}
+
+// ResetGroup removes only mocks of the group, persistent ones included.
+// Empty name removes nothing, as mocks without a group are not a group
+func (mc *MockCatcher) ResetGroup(name string) *MockCatcher {
+	if name == "" {
+		return mc
+	}
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mocks := make([]*FakeResponse, 0, len(mc.Mocks))
+	for _, fr := range mc.Mocks {
+		fr.mu.Lock()
+		group := fr.Group
+		fr.mu.Unlock()
+		if group != name {
+			mocks = append(mocks, fr)
+		}
+	}
+	mc.Mocks = mocks
+	return mc
+}
+
+// ResetAll removes all Mocks including persistent ones, interceptors, passthrough database and replay table,
+// and unfreezes mocks
+func (mc *MockCatcher) ResetAll() *MockCatcher {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.Mocks = make([]*FakeResponse, 0)
+	mc.history = nil
+	mc.tableErrors = nil
+	mc.transactions = nil
+	mc.interceptors = nil
+	mc.passthrough = nil
+	mc.frozen = false
+	mc.replay = nil
+	atomic.StoreInt64(&mc.quotaUsed, 0)
+	return mc
+}
+
+// Snapshot returns deep copy of the catcher mocks and settings, to be restored later by Restore
+func (mc *MockCatcher) Snapshot() *MockCatcher {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return &MockCatcher{
+		Mocks:                cloneMocks(mc.Mocks),
+		Logging:              mc.Logging,
+		PanicOnEmptyResponse: mc.PanicOnEmptyResponse,
+		StrictMatching:       mc.StrictMatching,
+		ErrorOnAmbiguous:     mc.ErrorOnAmbiguous,
+		OnConnect:            mc.OnConnect,
+		ConnectDelay:         mc.ConnectDelay,
+		ConnCloseError:       mc.ConnCloseError,
+		ReadOnlyExecError:    mc.ReadOnlyExecError,
+		QueryQuota:           mc.QueryQuota,
+		QuotaError:           mc.QuotaError,
+		ConnFactory:          mc.ConnFactory,
+		RewriteQuery:         mc.RewriteQuery,
+		Clock:                mc.Clock,
+		Dialect:              mc.Dialect,
+		DefaultRowsAffected:  mc.DefaultRowsAffected,
+		DefaultLastInsertId:  mc.DefaultLastInsertId,
+	}
+}
+
+// Restore replaces mocks and settings with a copy of the snapshot, so it could be restored several times
+func (mc *MockCatcher) Restore(snap *MockCatcher) *MockCatcher {
+	snap.mu.Lock()
+	mocks := cloneMocks(snap.Mocks)
+	logging, panicOnEmpty, onConnect, connCloseErr := snap.Logging, snap.PanicOnEmptyResponse, snap.OnConnect, snap.ConnCloseError
+	connFactory, rewriteQuery, connectDelay, strictMatching := snap.ConnFactory, snap.RewriteQuery, snap.ConnectDelay, snap.StrictMatching
+	readOnlyExecErr, queryQuota, quotaErr, clock, dialect := snap.ReadOnlyExecError, snap.QueryQuota, snap.QuotaError, snap.Clock, snap.Dialect
+	errorOnAmbiguous, defaultRowsAffected, defaultLastInsertID := snap.ErrorOnAmbiguous, snap.DefaultRowsAffected, snap.DefaultLastInsertId
+	snap.mu.Unlock()
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.Mocks = mocks
+	mc.Logging = logging
+	mc.PanicOnEmptyResponse = panicOnEmpty
+	mc.OnConnect = onConnect
+	mc.ConnCloseError = connCloseErr
+	mc.ConnFactory = connFactory
+	mc.RewriteQuery = rewriteQuery
+	mc.ConnectDelay = connectDelay
+	mc.StrictMatching = strictMatching
+	mc.ReadOnlyExecError = readOnlyExecErr
+	mc.QueryQuota = queryQuota
+	mc.QuotaError = quotaErr
+	mc.Clock = clock
+	mc.Dialect = dialect
+	mc.ErrorOnAmbiguous = errorOnAmbiguous
+	mc.DefaultRowsAffected = defaultRowsAffected
+	mc.DefaultLastInsertId = defaultLastInsertID
+	return mc
+}
+
+func cloneMocks(mocks []*FakeResponse) []*FakeResponse {
+	cloned := make([]*FakeResponse, len(mocks))
+	clones := make(map[*FakeResponse]*FakeResponse, len(mocks))
+	for i, fr := range mocks {
+		cloned[i] = fr.clone()
+		clones[fr] = cloned[i]
+	}
+	// Dependencies between cloned mocks point to clones
+	for _, fr := range cloned {
+		if clone, ok := clones[fr.Dependency]; ok {
+			fr.Dependency = clone
+		}
+	}
+	return cloned
+}
+
+// ColumnMeta declares type of a response column
+type ColumnMeta struct {
+	Name     string       // Column name as in Response rows
+	Type     string       // Database type name, e.g. VARCHAR(255), BIGINT or BLOB
+	ScanType reflect.Type // Go type to scan into, derived from Type if nil
+}
+
+// Exceptions represents	 possible exceptions during query executions
+type Exceptions struct {
+	HookQueryBadConnection func() bool
+	HookExecBadConnection  func() bool
+}
+
+// FakeResponse represents mock of response with holding all required values to return mocked response
+type FakeResponse struct {
+	Pattern        string                                    // SQL query pattern to match with
+	Patterns       []string                                  // Alternative patterns, query has to match any of them
+	Strict         bool                                      // Strict SQL query pattern comparison or by strings.Contains()
+	Binds          bool                                      // Compare Pattern and query with placeholders normalized to "?"
+	Fingerprint    bool                                      // Compare Pattern and query with literals replaced by placeholders
+	Placeholders   bool                                      // Query has to have as many placeholders as Pattern
+	InBatch        bool                                      // Match Pattern with any statement of semicolon separated batch
+	Forbidden      []string                                  // Substrings the query must not contain
+	Regexp         *regexp.Regexp                            // Regular expression the query has to match instead of Pattern
+	Args           []interface{}                             // List args to be matched with
+	NamedArgs      map[string]interface{}                    // Named args to be matched with by their names
+	ArgsHash       string                                    // HashArgs of args to be matched with
+	ArgTypes       []reflect.Type                            // Go types args have to be of, in addition to Args matching
+	FirstArg       interface{}                               // Value the first arg is matched with when ByFirstArg is set
+	ByFirstArg     bool                                      // Match the first arg with FirstArg ignoring the rest
+	ArgTransform   TransformFunc                             // Normalizes copy of incoming args before they are matched
+	ByOrdinal      bool                                      // Align incoming args to Args by their Ordinal instead of position
+	NumericLoose   bool                                      // Compare numeric Args by value regardless of their Go types
+	Response       []map[string]interface{}                  // Array of rows to be parsed as result
+	Table          []map[string]interface{}                  // Dataset to select rows from instead of Response
+	Pages          [][]map[string]interface{}                // Cursor pages emitted as result sets instead of Response
+	ReplyData      [][]driver.Value                          // Rows of values in Columns order emitted instead of Response
+	RoundRobin     [][]map[string]interface{}                // Replies emitted in turn by calls instead of Response, wrapping around
+	Stream         <-chan map[string]interface{}             // Rows emitted as they arrive instead of Response
+	FilterColumn   string                                    // Column of Table compared with the first arg
+	MaxRows        int                                       // Emit at most that many rows of Response, 0 means all
+	Paginate       bool                                      // Emit window of rows selected by LIMIT and OFFSET of the query
+	Columns        []string                                  // Declared response columns in order, taken from the first row if empty
+	ColumnTypes    []ColumnMeta                              // Declared types of response columns
+	Validate       bool                                      // Log warnings for response values database/sql can not scan
+	InferTypes     bool                                      // Infer undeclared column types from Go types of the first row values
+	Encoders       map[string]func(interface{}) driver.Value // Transform column values before emitting
+	OrderBy        string                                    // Column to sort Response rows by before emitting
+	OrderDesc      bool                                      // Sort by OrderBy column in descending order
+	FirstRowCols   bool                                      // Columns are sorted keys of the first row unless declared
+	Once           bool                                      // To trigger only once
+	Call           int                                       // Match only on the nth eligible call, 0 means on every call
+	FallThrough    int                                       // Stop matching after that many triggers, 0 means never
+	InTx           bool                                      // Matches only queries run inside transaction
+	NoTx           bool                                      // Matches only queries run outside of transaction
+	Isolation      sql.IsolationLevel                        // Isolation level of transaction to match when ByIsolation is set
+	ByIsolation    bool                                      // Match only queries inside transaction with Isolation level
+	ReadOnly       bool                                      // Match only queries inside read-only transaction
+	RequestID      string                                    // Matches only queries with this request id in context
+	Priority       int                                       // Mocks with higher priority are checked first
+	Dependency     *FakeResponse                             // Mock which has to be triggered before this one matches
+	ActiveFrom     int                                       // Match only after the catcher caught that many queries
+	ActiveUntil    int                                       // Match only until the catcher caught that many queries, 0 means forever
+	Group          string                                    // Name of the group to reset mocks selectively, see ResetGroup
+	Persistent     bool                                      // Survives Reset, only ResetAll removes it
+	Triggered      bool                                      // If it was triggered at least once
+	Expect         bool                                      // Call count is verified by AssertExpectations
+	ExpectedCalls  int                                       // How many times the mock has to be triggered when Expect is set
+	Callback       func(string, []driver.NamedValue)         // Callback to execute when response triggered
+	MetaCallback   MetaCallbackFunc                          // Callback receiving Meta of the mock when response triggered
+	ExhaustHook    func(*FakeResponse)                       // Called once the mock stops matching by OneTime or FallThroughAfter
+	Responder      ResponderFunc                             // Produces outcome of every execution instead of static fields
+	Meta           map[string]interface{}                    // Test metadata like scenario name recorded in history
+	Notices        []string                                  // Notices emitted by every query the mock serves
+	RowsAffected   int64                                     // Defines affected rows count
+	LastInsertID   int64                                     // ID to be returned for INSERT queries
+	Delay          time.Duration                             // Wait before responding, interrupted by context cancellation
+	Error          error                                     // Any type of error which could happen dur
+	RowError       error                                     // Error returned by rows iteration after RowErrorAt rows
+	RowErrorAt     int                                       // How many rows are emitted before RowError
+	RowErrors      map[int]error                             // Errors returned by rows iteration instead of rows at their index
+	ConvertErrors  map[string]error                          // Errors returned by Scan of columns instead of their values
+	StmtCloseError error                                     // Returned by Close of statements prepared for matching query
+	MaxStmtUses    int                                       // How many times statements prepared for matching query could run, 0 means unlimited
+	ReplyError     error                                     // Application error returned before exceptions are checked, never retried
+	mu             sync.Mutex                                // Used to lock concurrent access to variables
+	Capture        bool                                      // Record args of every call for assertions
+	calls          int                                       // How many times query and args were eligible for this mock
+	passthrough    *sql.DB                                   // Set on responses of queries forwarded to a real database
+	invocations    []Invocation                              // Calls recorded by CaptureArgs
+	lastExec       string                                    // Last Exec query the mock produced result for
+	emittedCols    []string                                  // Columns of the last result the mock served
+	regexpLiteral  string                                    // Literal every Regexp match contains, checked before Regexp
+	nextReply      int                                       // Index of RoundRobin reply emitted by the next call
+	triggered      int                                       // How many times response was returned
+	*Exceptions
+}
+
+// clone returns deep copy of the mock, funcs and errors are shared
+func (fr *FakeResponse) clone() *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	c := &FakeResponse{
+		Pattern:        fr.Pattern,
+		Strict:         fr.Strict,
+		Binds:          fr.Binds,
+		Fingerprint:    fr.Fingerprint,
+		Placeholders:   fr.Placeholders,
+		InBatch:        fr.InBatch,
+		Regexp:         fr.Regexp,
+		regexpLiteral:  fr.regexpLiteral,
+		ArgsHash:       fr.ArgsHash,
+		FirstArg:       fr.FirstArg,
+		ByFirstArg:     fr.ByFirstArg,
+		ArgTransform:   fr.ArgTransform,
+		ByOrdinal:      fr.ByOrdinal,
+		NumericLoose:   fr.NumericLoose,
+		FilterColumn:   fr.FilterColumn,
+		MaxRows:        fr.MaxRows,
+		Paginate:       fr.Paginate,
+		OrderBy:        fr.OrderBy,
+		OrderDesc:      fr.OrderDesc,
+		FirstRowCols:   fr.FirstRowCols,
+		Once:           fr.Once,
+		Call:           fr.Call,
+		FallThrough:    fr.FallThrough,
+		InTx:           fr.InTx,
+		NoTx:           fr.NoTx,
+		Isolation:      fr.Isolation,
+		ByIsolation:    fr.ByIsolation,
+		ReadOnly:       fr.ReadOnly,
+		RequestID:      fr.RequestID,
+		Priority:       fr.Priority,
+		Dependency:     fr.Dependency,
+		ActiveFrom:     fr.ActiveFrom,
+		ActiveUntil:    fr.ActiveUntil,
+		Group:          fr.Group,
+		Persistent:     fr.Persistent,
+		Triggered:      fr.Triggered,
+		Expect:         fr.Expect,
+		ExpectedCalls:  fr.ExpectedCalls,
+		Callback:       fr.Callback,
+		MetaCallback:   fr.MetaCallback,
+		ExhaustHook:    fr.ExhaustHook,
+		Responder:      fr.Responder,
+		RowsAffected:   fr.RowsAffected,
+		LastInsertID:   fr.LastInsertID,
+		Validate:       fr.Validate,
+		InferTypes:     fr.InferTypes,
+		Stream:         fr.Stream,
+		Delay:          fr.Delay,
+		Error:          fr.Error,
+		RowError:       fr.RowError,
+		RowErrorAt:     fr.RowErrorAt,
+		StmtCloseError: fr.StmtCloseError,
+		MaxStmtUses:    fr.MaxStmtUses,
+		ReplyError:     fr.ReplyError,
+		Capture:        fr.Capture,
+		calls:          fr.calls,
+		invocations:    append([]Invocation(nil), fr.invocations...),
+		triggered:      fr.triggered,
+		nextReply:      fr.nextReply,
+		lastExec:       fr.lastExec,
+		emittedCols:    append([]string(nil), fr.emittedCols...),
+	}
+	c.Patterns = append([]string(nil), fr.Patterns...)
+	c.Forbidden = append([]string(nil), fr.Forbidden...)
+	c.Notices = append([]string(nil), fr.Notices...)
+	if fr.Args != nil {
+		c.Args = make([]interface{}, len(fr.Args))
+		copy(c.Args, fr.Args)
+	}
+	c.ArgTypes = append([]reflect.Type(nil), fr.ArgTypes...)
+	if fr.NamedArgs != nil {
+		c.NamedArgs = make(map[string]interface{}, len(fr.NamedArgs))
+		for k, v := range fr.NamedArgs {
+			c.NamedArgs[k] = v
+		}
+	}
+	c.Columns = append([]string(nil), fr.Columns...)
+	c.ColumnTypes = append([]ColumnMeta(nil), fr.ColumnTypes...)
+	if fr.Meta != nil {
+		c.Meta = make(map[string]interface{}, len(fr.Meta))
+		for k, v := range fr.Meta {
+			c.Meta[k] = v
+		}
+	}
+	if fr.Encoders != nil {
+		c.Encoders = make(map[string]func(interface{}) driver.Value, len(fr.Encoders))
+		for k, v := range fr.Encoders {
+			c.Encoders[k] = v
+		}
+	}
+	if fr.RowErrors != nil {
+		c.RowErrors = make(map[int]error, len(fr.RowErrors))
+		for k, v := range fr.RowErrors {
+			c.RowErrors[k] = v
+		}
+	}
+	if fr.ConvertErrors != nil {
+		c.ConvertErrors = make(map[string]error, len(fr.ConvertErrors))
+		for k, v := range fr.ConvertErrors {
+			c.ConvertErrors[k] = v
+		}
+	}
+	c.Response = cloneRows(fr.Response)
+	c.Table = cloneRows(fr.Table)
+	if fr.Pages != nil {
+		c.Pages = make([][]map[string]interface{}, len(fr.Pages))
+		for i, page := range fr.Pages {
+			c.Pages[i] = cloneRows(page)
+		}
+	}
+	if fr.RoundRobin != nil {
+		c.RoundRobin = make([][]map[string]interface{}, len(fr.RoundRobin))
+		for i, reply := range fr.RoundRobin {
+			c.RoundRobin[i] = cloneRows(reply)
+		}
+	}
+	if fr.ReplyData != nil {
+		c.ReplyData = make([][]driver.Value, len(fr.ReplyData))
+		for i, values := range fr.ReplyData {
+			c.ReplyData[i] = append([]driver.Value(nil), values...)
+		}
+	}
+	if fr.Exceptions != nil {
+		exceptions := *fr.Exceptions
+		c.Exceptions = &exceptions
+	}
+	return c
+}
+
+func cloneRows(rows []map[string]interface{}) []map[string]interface{} {
+	if rows == nil {
+		return nil
+	}
+	cloned := make([]map[string]interface{}, len(rows))
+	for i, record := range rows {
+		cloned[i] = make(map[string]interface{}, len(record))
+		for k, v := range record {
+			cloned[i][k] = v
+		}
+	}
+	return cloned
+}
+
+// isArgsMatch returns true either when nothing to compare or deep equal check passed
+func (fr *FakeResponse) isArgsMatch(args []driver.NamedValue) bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	if fr.ArgTransform != nil {
+		args = fr.ArgTransform(append([]driver.NamedValue(nil), args...))
+	}
+	arguments := make([]interface{}, len(args))
+	if len(args) > 0 {
+		for index, arg := range args {
+			if fr.ByOrdinal {
+				// Ordinal is 1-based position of the parameter
+				if arg.Ordinal < 1 || arg.Ordinal > len(args) {
+					return false
+				}
+				index = arg.Ordinal - 1
+			}
+			arguments[index] = arg.Value
+		}
+	}
+	if fr.ArgsHash != "" && HashArgs(args) != fr.ArgsHash {
+		return false
+	}
+	if fr.ArgTypes != nil && !fr.isArgTypesMatch(arguments) {
+		return false
+	}
+	if fr.ByFirstArg && (len(arguments) == 0 || !fr.isArgEqual(fr.FirstArg, arguments[0])) {
+		return false
+	}
+	if fr.NamedArgs != nil && !fr.isNamedArgsMatch(args) {
+		return false
+	}
+	if fr.Args == nil {
+		return true
+	}
+	if len(fr.Args) != len(arguments) {
+		return false
+	}
+	for i, expected := range fr.Args {
+		if !fr.isArgEqual(expected, arguments[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isArgTypesMatch returns true if every arg has exactly the type in ArgTypes, fr.mu has to be held
+func (fr *FakeResponse) isArgTypesMatch(arguments []interface{}) bool {
+	if len(fr.ArgTypes) != len(arguments) {
+		return false
+	}
+	for i, typ := range fr.ArgTypes {
+		if reflect.TypeOf(arguments[i]) != typ {
+			return false
+		}
+	}
+	return true
+}
+
+// isNamedArgsMatch returns true if every named arg has equal value in NamedArgs, fr.mu has to be held
+func (fr *FakeResponse) isNamedArgsMatch(args []driver.NamedValue) bool {
+	named := 0
+	for _, arg := range args {
+		if arg.Name == "" {
+			continue
+		}
+		expected, ok := fr.NamedArgs[arg.Name]
+		if !ok || !fr.isArgEqual(expected, arg.Value) {
+			return false
+		}
+		named++
+	}
+	return named > 0
+}
+
+// isArgEqual compares single expected arg with incoming one, fr.mu has to be held.
+// Byte slices are compared by content, so nil and empty []byte are equal.
+// database/sql passes NULL, including nil pointers, as untyped nil: expected nil matches it only,
+// expected typed nil pointer matches it as well as nil pointer of the same type
+func (fr *FakeResponse) isArgEqual(expected, actual interface{}) bool {
+	if matcher, ok := expected.(ArgumentMatcher); ok {
+		return matcher.Match(actual)
+	}
+	if actual == nil {
+		return expected == nil || isNilPointer(expected)
+	}
+	if fr.NumericLoose && numbersEqual(expected, actual) {
+		return true
+	}
+	if expectedBytes, ok := expected.([]byte); ok {
+		actualBytes, ok := actual.([]byte)
+		return ok && bytes.Equal(expectedBytes, actualBytes)
+	}
+	return reflect.DeepEqual(expected, actual)
+}
+
+// isQueryMatch returns true if searched query is matched FakeResponse Pattern,
+// both normalized by the dialect of the catcher
+func (fr *FakeResponse) isQueryMatch(query string, dialect Dialect) bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	normalized := dialect.normalize(query)
+	for _, forbidden := range fr.Forbidden {
+		if strings.Contains(normalized, dialect.normalize(forbidden)) {
+			return false
+		}
+	}
+	if fr.InBatch {
+		_, ok := fr.batchStatement(query, dialect)
+		return ok
+	}
+	return fr.isStatementMatch(query, dialect)
+}
+
+// batchStatement returns the first statement of the batch the mock matches, fr.mu has to be held
+func (fr *FakeResponse) batchStatement(query string, dialect Dialect) (string, bool) {
+	for _, statement := range splitStatements(query) {
+		if fr.isStatementMatch(statement, dialect) {
+			return statement, true
+		}
+	}
+	return "", false
+}
+
+// execCommand returns command word of the statement the mock serves, for WithStatementInBatch mocks
+// it is taken from the matched statement instead of the first one of the batch
+func (fr *FakeResponse) execCommand(query string, dialect Dialect, command string) string {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	if !fr.InBatch {
+		return command
+	}
+	if statement, ok := fr.batchStatement(query, dialect); ok {
+		return strings.ToUpper(strings.Fields(statement)[0])
+	}
+	return command
+}
+
+// isStatementMatch compares Regexp with the raw statement, or Pattern and Patterns with the statement
+// normalized by the dialect, fr.mu has to be held
+func (fr *FakeResponse) isStatementMatch(query string, dialect Dialect) bool {
+	if fr.Regexp != nil {
+		return strings.Contains(query, fr.regexpLiteral) && fr.Regexp.MatchString(query)
+	}
+	query = dialect.normalize(query)
+	if len(fr.Patterns) > 0 {
+		for _, pattern := range fr.Patterns {
+			if fr.isPatternMatch(dialect.normalize(pattern), query) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if fr.Pattern == "" {
+		return true
+	}
+
+	return fr.isPatternMatch(dialect.normalize(fr.Pattern), query)
+}
+
+// isPatternMatch compares single pattern with the query, fr.mu has to be held
+func (fr *FakeResponse) isPatternMatch(pattern, query string) bool {
+	if fr.Binds {
+		pattern, query = normalizePlaceholders(pattern), normalizePlaceholders(query)
+	}
+	if fr.Fingerprint {
+		pattern, query = Fingerprint(pattern), Fingerprint(query)
+	}
+
+	if fr.Strict == true && query == pattern {
+		return true
+	}
+
+	if fr.Strict == false && strings.Contains(query, pattern) {
+		return true
+	}
+
+	return false
+}
+
+// IsMatch checks if both query and args matcher's return true and if this is Once mock
+func (fr *FakeResponse) IsMatch(query string, args []driver.NamedValue) bool {
+	return fr.isMatch(query, args, "")
+}
+
+// isMatch is IsMatch comparing query and patterns normalized by the dialect
+func (fr *FakeResponse) isMatch(query string, args []driver.NamedValue, dialect Dialect) bool {
+	return !fr.isExhausted() && fr.isDependencyMet() && fr.isQueryMatch(query, dialect) && fr.isArgsMatch(args)
+}
+
+// isPreparedMatch checks args of the mock bound to a prepared statement
+func (fr *FakeResponse) isPreparedMatch(args []driver.NamedValue) bool {
+	return !fr.isExhausted() && fr.isDependencyMet() && fr.isArgsMatch(args)
+}
+
+// isCallMatch checks requirements of the mock to the query execution besides query text and args
+func (fr *FakeResponse) isCallMatch(call *queryCall) bool {
+	return fr.isTxMatch(call.tx) && fr.isRequestMatch(call.requestID) && fr.isPlaceholderCountMatch(call.placeholders) &&
+		fr.isActive(call.caught)
+}
+
+// isActive checks whether the query caught after that many queries falls into ActiveBetween window of the mock
+func (fr *FakeResponse) isActive(caught int) bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return caught >= fr.ActiveFrom && (fr.ActiveUntil == 0 || caught < fr.ActiveUntil)
+}
+
+// isTxMatch checks WithinTx, OutsideTx, WithinReadOnlyTx and WithIsolation requirements of the mock
+func (fr *FakeResponse) isTxMatch(tx *FakeTx) bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	if fr.ReadOnly && (tx == nil || !tx.opts.ReadOnly) {
+		return false
+	}
+	if fr.ByIsolation && (tx == nil || sql.IsolationLevel(tx.opts.Isolation) != fr.Isolation) {
+		return false
+	}
+	return !(fr.InTx && tx == nil) && !(fr.NoTx && tx != nil)
+}
+
+// isRequestMatch checks WithRequestID requirement of the mock
+func (fr *FakeResponse) isRequestMatch(requestID string) bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.RequestID == "" || fr.RequestID == requestID
+}
+
+// isPlaceholderCountMatch checks MatchPlaceholderCount requirement of the mock
+func (fr *FakeResponse) isPlaceholderCountMatch(placeholders int) bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return !fr.Placeholders || countPlaceholders(fr.Pattern) == placeholders
+}
+
+// isDependencyMet returns true if the mock has no dependency or it was triggered already
+func (fr *FakeResponse) isDependencyMet() bool {
+	fr.mu.Lock()
+	dependency := fr.Dependency
+	fr.mu.Unlock()
+	if dependency == nil {
+		return true
+	}
+	dependency.mu.Lock()
+	defer dependency.mu.Unlock()
+	return dependency.Triggered
+}
+
+// isExhausted returns true if the mock can not be triggered anymore because of OneTime or FallThroughAfter
+func (fr *FakeResponse) isExhausted() bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.exhausted()
+}
+
+// exhausted is isExhausted for callers holding fr.mu
+func (fr *FakeResponse) exhausted() bool {
+	return fr.Once && fr.Triggered || fr.FallThrough > 0 && fr.triggered >= fr.FallThrough
+}
+
+// nextCall counts one more eligible call and reports if the mock should respond to it
+func (fr *FakeResponse) nextCall() bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.calls++
+	return fr.Call == 0 || fr.calls == fr.Call
+}
+
+// MarkAsTriggered marks response as executed. For one time catches it will not make this possible to execute anymore
+func (fr *FakeResponse) MarkAsTriggered() {
+	if fr.markTriggered() {
+		fr.ExhaustHook(fr)
+	}
+}
+
+// markTriggered marks response as executed and reports if it got exhausted by it and has ExhaustHook
+func (fr *FakeResponse) markTriggered() bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	wasExhausted := fr.exhausted()
+	fr.Triggered = true
+	fr.triggered++
+	return fr.ExhaustHook != nil && !wasExhausted && fr.exhausted()
+}
+
+// capture records the call if CaptureArgs is enabled
+func (fr *FakeResponse) capture(query string, args []driver.NamedValue, when time.Time) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	if !fr.Capture {
+		return
+	}
+	fr.invocations = append(fr.invocations, Invocation{
+		Query: query,
+		Args:  append([]driver.NamedValue(nil), args...),
+		When:  when,
+	})
+}
+
+// LastExecQuery returns text of the last Exec query the mock produced result for,
+// or empty string if it did not serve any Exec
+func (fr *FakeResponse) LastExecQuery() string {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.lastExec
+}
+
+func (fr *FakeResponse) recordExec(query string) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.lastExec = query
+}
+
+// EmittedColumns returns columns of the last result the mock served in order they were emitted,
+// which is random for columns taken from the first row of response
+func (fr *FakeResponse) EmittedColumns() []string {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return append([]string(nil), fr.emittedCols...)
+}
+
+func (fr *FakeResponse) recordColumns(cols []string) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.emittedCols = append([]string(nil), cols...)
+}
+
+// resetState clears trigger counts and captured calls
+func (fr *FakeResponse) resetState() {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Triggered = false
+	fr.triggered = 0
+	fr.calls = 0
+	fr.invocations = nil
+	fr.lastExec = ""
+	fr.emittedCols = nil
+	fr.nextReply = 0
+}
+
+// TriggeredCount returns how many times response was returned for a query
+func (fr *FakeResponse) TriggeredCount() int {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.triggered
+}
+
+// flags returns names of enabled matching and response options, fr.mu has to be held
+func (fr *FakeResponse) flags() []string {
+	var flags []string
+	if fr.Strict {
+		flags = append(flags, "strict")
+	}
+	if fr.Binds {
+		flags = append(flags, "normalize-placeholders")
+	}
+	if fr.Fingerprint {
+		flags = append(flags, "fingerprint")
+	}
+	if fr.InBatch {
+		flags = append(flags, "statement-in-batch")
+	}
+	if fr.Regexp != nil {
+		flags = append(flags, "regexp="+strconv.Quote(fr.Regexp.String()))
+	}
+	if len(fr.Forbidden) > 0 {
+		flags = append(flags, fmt.Sprintf("without=%q", fr.Forbidden))
+	}
+	if fr.ByOrdinal {
+		flags = append(flags, "by-ordinal")
+	}
+	if fr.NumericLoose {
+		flags = append(flags, "numeric-loose")
+	}
+	if fr.Once {
+		flags = append(flags, "once")
+	}
+	if fr.Call > 0 {
+		flags = append(flags, "on-call="+strconv.Itoa(fr.Call))
+	}
+	if fr.FallThrough > 0 {
+		flags = append(flags, "fall-through-after="+strconv.Itoa(fr.FallThrough))
+	}
+	if fr.Persistent {
+		flags = append(flags, "persistent")
+	}
+	if fr.Priority != 0 {
+		flags = append(flags, "priority="+strconv.Itoa(fr.Priority))
+	}
+	if fr.Dependency != nil {
+		flags = append(flags, "depends-on")
+	}
+	if fr.ActiveFrom > 0 || fr.ActiveUntil > 0 {
+		flags = append(flags, "active="+strconv.Itoa(fr.ActiveFrom)+".."+strconv.Itoa(fr.ActiveUntil))
+	}
+	if fr.Group != "" {
+		flags = append(flags, "group="+fr.Group)
+	}
+	if fr.InTx {
+		flags = append(flags, "within-tx")
+	}
+	if fr.NoTx {
+		flags = append(flags, "outside-tx")
+	}
+	if fr.ByIsolation {
+		flags = append(flags, "isolation="+fr.Isolation.String())
+	}
+	if fr.ReadOnly {
+		flags = append(flags, "read-only-tx")
+	}
+	if fr.RequestID != "" {
+		flags = append(flags, "request="+fr.RequestID)
+	}
+	if fr.Expect {
+		flags = append(flags, "expected-calls="+strconv.Itoa(fr.ExpectedCalls))
+	}
+	if fr.Error != nil {
+		flags = append(flags, "error")
+	}
+	if fr.ReplyError != nil {
+		flags = append(flags, "reply-error")
+	}
+	if fr.Exceptions != nil && fr.Exceptions.HookQueryBadConnection != nil {
+		flags = append(flags, "query-exception")
+	}
+	if fr.Exceptions != nil && fr.Exceptions.HookExecBadConnection != nil {
+		flags = append(flags, "exec-exception")
+	}
+	return flags
+}
+
+// WithQuery adds SQL query pattern to match for
+func (fr *FakeResponse) WithQuery(query string) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Pattern = query
+	return fr
+}
+
+// WithQueryRegexp makes the mock match queries by regular expression instead of Pattern. The expression is
+// compiled once here, and queries missing literal text the expression requires are skipped without running it.
+// The expression is matched against the query as it was sent, Dialect normalization does not apply to it.
+// It panics if the expression does not compile, like regexp.MustCompile
+func (fr *FakeResponse) WithQueryRegexp(expr string) *FakeResponse {
+	re := regexp.MustCompile(expr)
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Regexp = re
+	fr.regexpLiteral = requiredLiteral(re)
+	return fr
+}
+
+// WithoutQuerySubstring makes the mock match only queries containing none of substrings,
+// in addition to its patterns, e.g. SELECT from users without FOR UPDATE
+func (fr *FakeResponse) WithoutQuerySubstring(substrings ...string) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Forbidden = append(fr.Forbidden, substrings...)
+	return fr
+}
+
+// WithStatementInBatch adds SQL query pattern matched with every statement of semicolon separated batch,
+// the mock matches if any of them matches. Semicolons inside quoted strings do not split statements,
+// Exec result is built for the command of the matched statement, so batches may start with DDL
+func (fr *FakeResponse) WithStatementInBatch(pattern string) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Pattern = pattern
+	fr.InBatch = true
+	return fr
+}
+
+// WithAnyQuery adds several SQL query patterns, the mock matches if any of them matches the query
+func (fr *FakeResponse) WithAnyQuery(patterns ...string) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Patterns = patterns
+	return fr
+}
+
+// WithQueryFingerprint adds SQL query pattern which matches regardless of literal values,
+// e.g. `WHERE id = 1` matches `WHERE id = 2` and `WHERE id = ?`
+func (fr *FakeResponse) WithQueryFingerprint(template string) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Pattern = template
+	fr.Fingerprint = true
+	return fr
+}
+
+// NormalizePlaceholders makes one pattern match queries of any placeholder style:
+// `WHERE id = ?`, `WHERE id = $1` and `WHERE id = :id` are compared as `WHERE id = ?`
+func (fr *FakeResponse) NormalizePlaceholders() *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Binds = true
+	return fr
+}
+
+// MatchPlaceholderCount additionally requires the query to have exactly as many placeholders as Pattern,
+// so a pattern of bulk insert does not match inserts with more rows. Placeholders are counted in statement text
+// before args are interpolated into it
+func (fr *FakeResponse) MatchPlaceholderCount() *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Placeholders = true
+	return fr
+}
+
+// WithQuery adds SQL query pattern to match for
+func (fr *FakeResponse) StrictMatch() *FakeResponse {
+	fr.Strict = true
+	return fr
+}
+
+// WithArgs attaches Args check for prepared statements
+func (fr *FakeResponse) WithArgs(vars ...interface{}) *FakeResponse {
+	if len(vars) > 0 {
+		fr.Args = make([]interface{}, len(vars))
+		for index, v := range vars {
+			fr.Args[index] = v
+		}
+	}
+	return fr
+}
+
+// CaptureArgs records query and args of every call served by the mock, to be checked by AssertArgAt and others
+func (fr *FakeResponse) CaptureArgs() *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Capture = true
+	return fr
+}
+
+// WithNamedArgs attaches check of named args, e.g. passed via sql.Named().
+// Every named arg of the query has to be in the map with equal value, the map may hold more values than the query uses
+func (fr *FakeResponse) WithNamedArgs(args map[string]interface{}) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.NamedArgs = args
+	return fr
+}
+
+// WithArgsStruct attaches check of named args taken from struct fields like WithNamedArgs.
+// Names are taken from `db` tags or field names, fields tagged with `db:"-"` are skipped
+func (fr *FakeResponse) WithArgsStruct(v interface{}) *FakeResponse {
+	return fr.WithNamedArgs(structToNamedArgs(v))
+}
+
+// WithArgTransform normalizes incoming args, e.g. lowercases strings or rounds floats, before they are matched
+// by any args check of the mock. Transform gets a copy of args, so it could change them in place
+func (fr *FakeResponse) WithArgTransform(transform TransformFunc) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.ArgTransform = transform
+	return fr
+}
+
+// WithFirstArg matches queries by their first arg only, e.g. tenant id, ignoring the rest.
+// Queries without args never match
+func (fr *FakeResponse) WithFirstArg(v interface{}) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.FirstArg = v
+	fr.ByFirstArg = true
+	return fr
+}
+
+// WithArgTypes additionally requires every incoming arg to have exactly given Go type, e.g. int64 and not string,
+// after database/sql converted it. Use nil type for NULL args
+func (fr *FakeResponse) WithArgTypes(types ...reflect.Type) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.ArgTypes = types
+	return fr
+}
+
+// WithArgsHash matches args by their HashArgs instead of comparing values one by one,
+// which is faster and less brittle for large args. Precompute the hash with HashArgs
+func (fr *FakeResponse) WithArgsHash(hash string) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.ArgsHash = hash
+	return fr
+}
+
+// MatchByOrdinal aligns incoming args to Args by their Ordinal field instead of position,
+// for drivers which pass named values out of order
+func (fr *FakeResponse) MatchByOrdinal() *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.ByOrdinal = true
+	return fr
+}
+
+// NumericLooseMatch compares numeric Args by value, so WithArgs(27) matches int64(27) sent by the driver
+// and float32(1.5) matches float64(1.5)
+func (fr *FakeResponse) NumericLooseMatch() *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.NumericLoose = true
+	return fr
+}
+
+// WithReply adds to chain and assign some parts of response.
+// A value of type func() driver.Value is computed lazily, it is called every time its row is emitted
+func (fr *FakeResponse) WithReply(response []map[string]interface{}) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Response = response
+	return fr
+}
+
+// WithRoundRobinReplies makes calls emit the replies in turn, starting over after the last one.
+// ResetState starts again from the first reply
+func (fr *FakeResponse) WithRoundRobinReplies(replies ...[]map[string]interface{}) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.RoundRobin = replies
+	fr.nextReply = 0
+	return fr
+}
+
+// roundRobinReply returns RoundRobin reply of the current call and moves to the next one
+func (fr *FakeResponse) roundRobinReply() []map[string]interface{} {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	reply := fr.RoundRobin[fr.nextReply%len(fr.RoundRobin)]
+	fr.nextReply = (fr.nextReply + 1) % len(fr.RoundRobin)
+	return reply
+}
+
+// WithReplyData sets response as rows of values in order of columns, e.g. golden data captured from
+// a real database. Values are emitted as they are without conversion from maps, nil values are NULLs.
+// It is the form every other reply is converted to, so encoders, FilterBy, WithOrderBy, Paginated
+// and WithMaxRows apply to it as well
+func (fr *FakeResponse) WithReplyData(columns []string, rows [][]driver.Value) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Columns = columns
+	fr.ReplyData = rows
+	return fr
+}
+
+// WithColumns declares response columns and their order. Rows missing a column emit NULL for it,
+// and columns are reported by rows.Columns() even when there are no rows
+func (fr *FakeResponse) WithColumns(columns ...string) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Columns = columns
+	return fr
+}
+
+// ColumnsFromFirstRow makes result columns the sorted keys of the first row instead of keys of all rows,
+// keys missing in the first row are not emitted. Columns declared by WithColumns take precedence
+func (fr *FakeResponse) ColumnsFromFirstRow() *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.FirstRowCols = true
+	return fr
 }
 
-func (mc *MockCatcher) SetLogging(l bool) {
-	mc.mu.Lock()
-	defer mc.mu.Unlock()
-	mc.Logging = l
+// WithNoRows sets empty response, combined with WithColumns it emits only the columns
+func (fr *FakeResponse) WithNoRows() *FakeResponse {
+	return fr.WithReply(make([]map[string]interface{}, 0))
 }
 
-// Register safely register FakeDriver
-func (mc *MockCatcher) Register() {
-	driversList := sql.Drivers()
-	for _, name := range driversList {
-		if name == DriverName {
-			return
+// WithColumnTypes declares database types of response columns, reported by rows.ColumnTypes().
+// Scan type is derived from the database type unless ColumnMeta.ScanType is set
+func (fr *FakeResponse) WithColumnTypes(columns ...ColumnMeta) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.ColumnTypes = columns
+	return fr
+}
+
+// columnMeta returns declared type of the column
+func (fr *FakeResponse) columnMeta(name string) (ColumnMeta, bool) {
+	for _, meta := range fr.ColumnTypes {
+		if meta.Name == name {
+			return meta, true
 		}
 	}
-	sql.Register(DriverName, &FakeDriver{})
+	return ColumnMeta{}, false
 }
 
-// Attach several mocks to MockCather. Could be useful to attach mocks from some factories of mocks
-func (mc *MockCatcher) Attach(fr []*FakeResponse) {
-	mc.Mocks = append(mc.Mocks, fr...)
+// InferColumnTypes makes columns without declared type report database type inferred from Go type
+// of their value in the first row: BIGINT for integers, DOUBLE, VARCHAR, BOOLEAN, TIMESTAMP and BLOB
+func (fr *FakeResponse) InferColumnTypes() *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.InferTypes = true
+	return fr
 }
 
-// FindResponse finds suitable response by provided
-func (mc *MockCatcher) FindResponse(query string, args []driver.NamedValue) *FakeResponse {
-	mc.mu.Lock()
-	defer mc.mu.Unlock()
-	if mc.Logging {
-		log.Printf("mock_catcher: check query: %s", query)
+// ValidateScannable logs a warning when a response value has a type database/sql can not scan,
+// e.g. a struct put into the response instead of its column value
+func (fr *FakeResponse) ValidateScannable() *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Validate = true
+	return fr
+}
+
+// WithValueEncoder transforms Go values of the column to driver values before they are emitted,
+// e.g. to encode json, jsonb or inet columns the way driver specific code expects them
+func (fr *FakeResponse) WithValueEncoder(column string, encoder func(interface{}) driver.Value) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	if fr.Encoders == nil {
+		fr.Encoders = make(map[string]func(interface{}) driver.Value)
 	}
+	fr.Encoders[column] = encoder
+	return fr
+}
 
-	for _, resp := range mc.Mocks {
-		if resp.IsMatch(query, args) {
-			resp.MarkAsTriggered()
-			return resp
-		}
+// WithTable holds full dataset to emit rows from, use FilterBy to select only rows matching the query arg
+func (fr *FakeResponse) WithTable(rows []map[string]interface{}) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Table = rows
+	return fr
+}
+
+// FilterBy emits only rows of the WithTable dataset or of the reply whose column value equals the first query arg.
+// Numbers are compared by value regardless of their types, the column has to be emitted
+func (fr *FakeResponse) FilterBy(column string) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.FilterColumn = column
+	return fr
+}
+
+// WithMaxRows caps rows emitted from Response to n, e.g. to simulate LIMIT.
+// If Response has fewer rows all of them are returned
+func (fr *FakeResponse) WithMaxRows(n int) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.MaxRows = n
+	return fr
+}
+
+// Paginated makes the mock emit window of Response or Table rows selected by trailing `LIMIT n OFFSET m`,
+// `LIMIT n` or `LIMIT m, n` of the query, so one mock serves every page. Parsing is best-effort,
+// queries without such clause get all rows
+func (fr *FakeResponse) Paginated() *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Paginate = true
+	return fr
+}
+
+// WithOrderBy sorts Response rows by the column value before emitting, to emulate ORDER BY.
+// Values of different types are not mixed: NULLs go first, then bools, numbers, strings and times
+func (fr *FakeResponse) WithOrderBy(column string, desc bool) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.OrderBy = column
+	fr.OrderDesc = desc
+	return fr
+}
+
+// WithGeneratedRows sets response of n rows built by generate, called with row index from 0 to n-1
+func (fr *FakeResponse) WithGeneratedRows(n int, generate func(i int) map[string]interface{}) *FakeResponse {
+	response := make([]map[string]interface{}, n)
+	for i := range response {
+		response[i] = generate(i)
 	}
+	return fr.WithReply(response)
+}
 
-	if mc.PanicOnEmptyResponse {
-		panic(fmt.Sprintf("No responses matches query %s ", query))
+// WithReplyInts sets a single column response with one row per value
+func (fr *FakeResponse) WithReplyInts(column string, values []int64) *FakeResponse {
+	response := make([]map[string]interface{}, len(values))
+	for i, v := range values {
+		response[i] = map[string]interface{}{column: v}
 	}
+	return fr.WithReply(response)
+}
 
-	// Let's have always dummy version of response
-	return &FakeResponse{
-		Response:   make([]map[string]interface{}, 0),
-		Exceptions: &Exceptions{},
+// WithReplyFloats sets a single column response with one row per value
+func (fr *FakeResponse) WithReplyFloats(column string, values []float64) *FakeResponse {
+	response := make([]map[string]interface{}, len(values))
+	for i, v := range values {
+		response[i] = map[string]interface{}{column: v}
 	}
+	return fr.WithReply(response)
 }
 
-// NewMock creates new FakeResponse and return for chains of attachments
-func (mc *MockCatcher) NewMock() *FakeResponse {
-	mc.mu.Lock()
-	defer mc.mu.Unlock()
-	fr := &FakeResponse{Exceptions: &Exceptions{}, Response: make([]map[string]interface{}, 0)}
-	mc.Mocks = append(mc.Mocks, fr)
+// WithReplyStrings sets a single column response with one row per value
+func (fr *FakeResponse) WithReplyStrings(column string, values []string) *FakeResponse {
+	response := make([]map[string]interface{}, len(values))
+	for i, v := range values {
+		response[i] = map[string]interface{}{column: v}
+	}
+	return fr.WithReply(response)
+}
+
+// WithReplyBools sets a single column response with one row per value
+func (fr *FakeResponse) WithReplyBools(column string, values []bool) *FakeResponse {
+	response := make([]map[string]interface{}, len(values))
+	for i, v := range values {
+		response[i] = map[string]interface{}{column: v}
+	}
+	return fr.WithReply(response)
+}
+
+// WithCursorPages emulates server-side cursor, the first page is the rows of the query and every
+// NextResultSet fetches the next page until they run out. Pages share columns, taken from the first row of
+// the first page unless declared by WithColumns. Every query opens a new cursor starting from the first page
+func (fr *FakeResponse) WithCursorPages(pages ...[]map[string]interface{}) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Pages = pages
 	return fr
 }
 
-// Reset removes all Mocks to start process again
-func (mc *MockCatcher) Reset() *MockCatcher {
-	mc.mu.Lock()
-	defer mc.mu.Unlock()
-	mc.Mocks = make([]*FakeResponse, 0)
-	return mc
+// WithReplyChannel makes rows iteration emit rows as they are sent to the channel until it is closed,
+// waiting for a row is interrupted by cancellation of the query context. Columns have to be declared
+// by WithColumns, since they are needed before the first row arrives. The channel is consumed by
+// all queries the mock serves, so it fits one streaming query
+func (fr *FakeResponse) WithReplyChannel(ch <-chan map[string]interface{}) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Stream = ch
+	return fr
 }
 
-// Exceptions represents	 possible exceptions during query executions
-type Exceptions struct {
-	HookQueryBadConnection func() bool
-	HookExecBadConnection  func() bool
+// OneTime sets current mock to be triggered only once
+func (fr *FakeResponse) OneTime() *FakeResponse {
+	fr.Once = true
+	return fr
 }
 
-// FakeResponse represents mock of response with holding all required values to return mocked response
-type FakeResponse struct {
-	Pattern      string                            // SQL query pattern to match with
-	Strict       bool                              // Strict SQL query pattern comparison or by strings.Contains()
-	Args         []interface{}                     // List args to be matched with
-	Response     []map[string]interface{}          // Array of rows to be parsed as result
-	Once         bool                              // To trigger only once
-	Triggered    bool                              // If it was triggered at least once
-	Callback     func(string, []driver.NamedValue) // Callback to execute when response triggered
-	RowsAffected int64                             // Defines affected rows count
-	LastInsertID int64                             // ID to be returned for INSERT queries
-	Error        error                             // Any type of error which could happen dur
-	mu           sync.Mutex                        // Used to lock concurrent access to variables
-	*Exceptions
+// DependsOn makes the mock match only after other mock was triggered, e.g. SELECT of a row after its INSERT
+func (fr *FakeResponse) DependsOn(other *FakeResponse) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Dependency = other
+	return fr
 }
 
-// isArgsMatch returns true either when nothing to compare or deep equal check passed
-func (fr *FakeResponse) isArgsMatch(args []driver.NamedValue) bool {
+// ActiveBetween makes the mock match only while the catcher has caught at least min and less than max
+// queries since last Reset, counting queries served by any mock. Max 0 leaves the window open,
+// e.g. ActiveBetween(2, 0) skips the first two queries
+func (fr *FakeResponse) ActiveBetween(min, max int) *FakeResponse {
 	fr.mu.Lock()
 	defer fr.mu.Unlock()
-	arguments := make([]interface{}, len(args))
-	if len(args) > 0 {
-		for index, arg := range args {
-			arguments[index] = arg.Value
-		}
-	}
-	return fr.Args == nil || reflect.DeepEqual(fr.Args, arguments)
+	fr.ActiveFrom = min
+	fr.ActiveUntil = max
+	return fr
 }
 
-// isQueryMatch returns true if searched query is matched FakeResponse Pattern
-func (fr *FakeResponse) isQueryMatch(query string) bool {
+// WithPriority makes the mock checked before mocks with lower priority regardless of registration order,
+// mocks have priority 0 by default
+func (fr *FakeResponse) WithPriority(priority int) *FakeResponse {
 	fr.mu.Lock()
-        defer fr.mu.Unlock()
-	
-	if fr.Pattern == "" {
-		return true
-	}
+	defer fr.mu.Unlock()
+	fr.Priority = priority
+	return fr
+}
 
-	if fr.Strict == true && query == fr.Pattern {
-		return true
-	}
+func (fr *FakeResponse) priority() int {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.Priority
+}
 
-	if fr.Strict == false && strings.Contains(query, fr.Pattern) {
-		return true
-	}
+// isLimited reports whether the mock serves only some calls because of OnCall, OneTime or FallThroughAfter
+func (fr *FakeResponse) isLimited() bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.Once || fr.Call > 0 || fr.FallThrough > 0
+}
 
-	return false
+// WithGroup puts current mock into the group, so it could be removed by ResetGroup
+func (fr *FakeResponse) WithGroup(name string) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Group = name
+	return fr
 }
 
-// IsMatch checks if both query and args matcher's return true and if this is Once mock
-func (fr *FakeResponse) IsMatch(query string, args []driver.NamedValue) bool {
+// Persist keeps current mock attached after Reset. Use ResetAll to remove it
+func (fr *FakeResponse) Persist() *FakeResponse {
 	fr.mu.Lock()
-	if fr.Once && fr.Triggered {
-		fr.mu.Unlock()
-		return false
-	}
-	fr.mu.Unlock()
-	return fr.isQueryMatch(query) && fr.isArgsMatch(args)
+	defer fr.mu.Unlock()
+	fr.Persistent = true
+	return fr
 }
 
-// MarkAsTriggered marks response as executed. For one time catches it will not make this possible to execute anymore
-func (fr *FakeResponse) MarkAsTriggered() {
+func (fr *FakeResponse) isPersistent() bool {
 	fr.mu.Lock()
 	defer fr.mu.Unlock()
-	fr.Triggered = true
+	return fr.Persistent
 }
 
-// WithQuery adds SQL query pattern to match for
-func (fr *FakeResponse) WithQuery(query string) *FakeResponse {
+// WithinTx makes the mock match only queries run inside transaction begun on the connection
+func (fr *FakeResponse) WithinTx() *FakeResponse {
 	fr.mu.Lock()
 	defer fr.mu.Unlock()
-	fr.Pattern = query
+	fr.InTx = true
+	fr.NoTx = false
 	return fr
 }
 
-// WithQuery adds SQL query pattern to match for
-func (fr *FakeResponse) StrictMatch() *FakeResponse {
-	fr.Strict = true
+// OutsideTx makes the mock match only queries run outside of any transaction
+func (fr *FakeResponse) OutsideTx() *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.NoTx = true
+	fr.InTx = false
 	return fr
 }
 
-// WithArgs attaches Args check for prepared statements
-func (fr *FakeResponse) WithArgs(vars ...interface{}) *FakeResponse {
-	if len(vars) > 0 {
-		fr.Args = make([]interface{}, len(vars))
-		for index, v := range vars {
-			fr.Args[index] = v
-		}
-	}
+// WithinReadOnlyTx makes the mock match only queries inside transaction begun with ReadOnly option
+func (fr *FakeResponse) WithinReadOnlyTx() *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.ReadOnly = true
 	return fr
 }
 
-// WithReply adds to chain and assign some parts of response
-func (fr *FakeResponse) WithReply(response []map[string]interface{}) *FakeResponse {
+// WithIsolation makes the mock match only queries inside transaction begun by BeginTx with given isolation level,
+// sql.LevelDefault matches transactions begun without options
+func (fr *FakeResponse) WithIsolation(level sql.IsolationLevel) *FakeResponse {
 	fr.mu.Lock()
 	defer fr.mu.Unlock()
-	fr.Response = response
+	fr.Isolation = level
+	fr.ByIsolation = true
 	return fr
 }
 
-// OneTime sets current mock to be triggered only once
-func (fr *FakeResponse) OneTime() *FakeResponse {
-	fr.Once = true
+// WithRequestID makes the mock match only queries run with context from ContextWithRequestID with the same id
+func (fr *FakeResponse) WithRequestID(id string) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.RequestID = id
+	return fr
+}
+
+// OnCall sets current mock to respond only on the nth call which matches its query and args.
+// Calls are counted per mock, even when an earlier mock in the list served the query,
+// so several mocks with the same pattern and different OnCall values script a sequence.
+// Mocks without OnCall still respond on every call, so put them after the OnCall ones
+func (fr *FakeResponse) OnCall(n int) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Call = n
+	return fr
+}
+
+// OnExhausted sets hook called once when the mock stops matching because OneTime or FallThroughAfter
+// limit is used up, right after serving its last query
+func (fr *FakeResponse) OnExhausted(hook func(fr *FakeResponse)) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.ExhaustHook = hook
+	return fr
+}
+
+// FallThroughAfter makes current mock serve n queries, after that it is skipped
+// and subsequent queries are handled by the next matching mock
+func (fr *FakeResponse) FallThroughAfter(n int) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.FallThrough = n
+	return fr
+}
+
+// WithMeta attaches test metadata to the mock, it is recorded in History of queries the mock serves
+// and passed to callbacks set by WithMetaCallback
+func (fr *FakeResponse) WithMeta(meta map[string]interface{}) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Meta = meta
+	return fr
+}
+
+func (fr *FakeResponse) meta() map[string]interface{} {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.Meta
+}
+
+// WithNotices makes every query the mock serves emit notices, retrieved later by Catcher.Notices
+// or from History records
+func (fr *FakeResponse) WithNotices(notices []string) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Notices = notices
 	return fr
 }
 
+func (fr *FakeResponse) notices() []string {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return append([]string(nil), fr.Notices...)
+}
+
 // WithExecException says that if mock attached to non-SELECT query we need to trigger error there
 func (fr *FakeResponse) WithExecException() *FakeResponse {
 	fr.Exceptions.HookExecBadConnection = func() bool {
@@ -225,6 +2165,25 @@ func (fr *FakeResponse) WithCallback(f func(string, []driver.NamedValue)) *FakeR
 	return fr
 }
 
+// WithMetaCallback adds callback to be executed during matching with Meta of the mock which served the query,
+// so callbacks of mocks copied by Register or Restore see their own meta
+func (fr *FakeResponse) WithMetaCallback(f MetaCallbackFunc) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.MetaCallback = f
+	return fr
+}
+
+// WithResponder makes the mock produce rows, result values and error of every execution by responder,
+// e.g. branching on args, instead of WithReply, WithRowsNum, WithID and WithError. Query uses rows and error,
+// Exec uses rows affected, insert id and error, zero values are defaulted for INSERT like static ones
+func (fr *FakeResponse) WithResponder(responder ResponderFunc) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Responder = responder
+	return fr
+}
+
 // WithRowsNum specifies how many records to consider as affected
 func (fr *FakeResponse) WithRowsNum(num int64) *FakeResponse {
 	fr.RowsAffected = num
@@ -237,13 +2196,102 @@ func (fr *FakeResponse) WithID(id int64) *FakeResponse {
 	return fr
 }
 
+// WithDelay makes the driver wait d before responding. Query fails with context error if it is done earlier
+func (fr *FakeResponse) WithDelay(d time.Duration) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.Delay = d
+	return fr
+}
+
+// wait blocks for the response Delay measured by clock or until ctx is done
+func (fr *FakeResponse) wait(ctx context.Context, clock Clock) error {
+	if fr.Delay <= 0 {
+		return nil
+	}
+	select {
+	case <-clock.After(fr.Delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WithResult sets both ID of inserted record and affected rows count, like WithID and WithRowsNum
+func (fr *FakeResponse) WithResult(lastInsertID, rowsAffected int64) *FakeResponse {
+	return fr.WithID(lastInsertID).WithRowsNum(rowsAffected)
+}
+
 // WithError sets Error to FakeResponse struct to have it available on any statements executed
-// example: WithError(sql.ErrNoRows)
+// example: WithError(sql.ErrNoRows). The error reaches the caller as it is, not wrapped by the driver,
+// so errors.As finds custom error types and interfaces like Temporary() bool
 func (fr *FakeResponse) WithError(err error) *FakeResponse {
 	fr.Error = err
 	return fr
 }
 
+// WithRowError makes rows iteration fail with err after afterRow rows were emitted,
+// database/sql surfaces it via rows.Err(). If response has fewer rows iteration ends without error
+func (fr *FakeResponse) WithRowError(afterRow int, err error) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.RowErrorAt = afterRow
+	fr.RowError = err
+	return fr
+}
+
+// WithReplyError makes Query and Exec return application error like a constraint violation instead of
+// any exception, so database/sql does not retry them. Errors wrapping driver.ErrBadConn are kept by message only,
+// as database/sql retries queries failed with it
+func (fr *FakeResponse) WithReplyError(err error) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	if errors.Is(err, driver.ErrBadConn) {
+		err = errors.New(err.Error())
+	}
+	fr.ReplyError = err
+	return fr
+}
+
+// WithMaxStmtUses makes statements prepared for matching query fail with ErrStmtExpired after n executions,
+// like statements expired on the server side. Every prepared statement counts its own uses
+func (fr *FakeResponse) WithMaxStmtUses(n int) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.MaxStmtUses = n
+	return fr
+}
+
+// WithStmtCloseError makes Close of statements prepared for matching query return err.
+// database/sql ignores it for statements prepared on *sql.DB, it is returned for statements of *sql.Conn and *sql.Tx
+func (fr *FakeResponse) WithStmtCloseError(err error) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.StmtCloseError = err
+	return fr
+}
+
+// WithRowErrors makes rows iteration fail when it reaches rows with given 0-based indexes,
+// rows before the first of them are emitted as usual
+func (fr *FakeResponse) WithRowErrors(errs map[int]error) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.RowErrors = errs
+	return fr
+}
+
+// WithConvertError makes Scan of the column fail with err wrapped by database/sql scan error,
+// so errors.Is finds it. It needs Go 1.27 drivers scanning columns themselves, older versions scan values as usual
+func (fr *FakeResponse) WithConvertError(column string, err error) *FakeResponse {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	if fr.ConvertErrors == nil {
+		fr.ConvertErrors = make(map[string]error)
+	}
+	fr.ConvertErrors[column] = err
+	return fr
+}
+
 func init() {
 	Catcher = &MockCatcher{}
 }
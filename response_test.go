@@ -1,9 +1,21 @@
 package gomocket
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"math/rand"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 var DB *sql.DB
@@ -210,3 +222,1747 @@ func TestResponses(t *testing.T) {
 		})
 	})
 }
+
+func TestOnCall(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+
+	t.Run("Script first, second and third calls", func(t *testing.T) {
+		Catcher.Reset()
+		Catcher.NewMock().WithQuery("SELECT name FROM users").OnCall(1).WithReply([]map[string]interface{}{{"name": "first"}})
+		Catcher.NewMock().WithQuery("SELECT name FROM users").OnCall(2).WithReply([]map[string]interface{}{{"name": "second"}})
+		Catcher.NewMock().WithQuery("SELECT name FROM users").OnCall(3).WithReply([]map[string]interface{}{{"name": "third"}})
+
+		for _, expected := range []string{"first", "second", "third"} {
+			var name string
+			if err := db.QueryRow("SELECT name FROM users").Scan(&name); err != nil {
+				t.Fatalf("Query failed [%v]", err)
+			}
+			if name != expected {
+				t.Errorf("Name mismatches. Expected: [%v] , Got: [%v]", expected, name)
+			}
+		}
+
+		rows, _ := db.Query("SELECT name FROM users")
+		if rows.Next() {
+			t.Errorf("Fourth call should not be matched")
+		}
+		rows.Close()
+	})
+
+	t.Run("Falls back to general mock on other calls", func(t *testing.T) {
+		Catcher.Reset()
+		Catcher.NewMock().WithQuery("SELECT name FROM users").OnCall(2).WithReply([]map[string]interface{}{{"name": "special"}})
+		Catcher.NewMock().WithQuery("SELECT name FROM users").WithReply([]map[string]interface{}{{"name": "common"}})
+
+		for _, expected := range []string{"common", "special", "common"} {
+			var name string
+			if err := db.QueryRow("SELECT name FROM users").Scan(&name); err != nil {
+				t.Fatalf("Query failed [%v]", err)
+			}
+			if name != expected {
+				t.Errorf("Name mismatches. Expected: [%v] , Got: [%v]", expected, name)
+			}
+		}
+	})
+}
+
+func TestPersist(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	defer Catcher.ResetAll()
+
+	getName := func(query string) string {
+		var name string
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Fatalf("Query failed [%v]", err)
+		}
+		defer rows.Close()
+		if rows.Next() {
+			rows.Scan(&name)
+		}
+		return name
+	}
+
+	t.Run("Persistent mocks survive Reset", func(t *testing.T) {
+		Catcher.ResetAll()
+		Catcher.NewMock().WithQuery("SELECT name FROM settings").Persist().WithReply([]map[string]interface{}{{"name": "base"}})
+		Catcher.NewMock().WithQuery("SELECT name FROM users").WithReply([]map[string]interface{}{{"name": "user"}})
+
+		Catcher.Reset()
+		if len(Catcher.Mocks) != 1 {
+			t.Fatalf("Mocks count mismatches. Expected: [%v] , Got: [%v]", 1, len(Catcher.Mocks))
+		}
+		if name := getName("SELECT name FROM settings"); name != "base" {
+			t.Errorf("Persistent mock not matched. Got: [%v]", name)
+		}
+		if name := getName("SELECT name FROM users"); name != "" {
+			t.Errorf("Regular mock should be removed by Reset. Got: [%v]", name)
+		}
+	})
+
+	t.Run("One time persistent mock matches after every Reset", func(t *testing.T) {
+		Catcher.ResetAll()
+		Catcher.NewMock().WithQuery("SELECT name FROM settings").Persist().OneTime().WithReply([]map[string]interface{}{{"name": "base"}})
+		defer Catcher.ResetAll()
+
+		for i := 0; i < 2; i++ {
+			Catcher.Reset()
+			if name := getName("SELECT name FROM settings"); name != "base" {
+				t.Errorf("Persistent one time mock not matched after Reset %d. Got: [%v]", i, name)
+			}
+		}
+	})
+
+	t.Run("ResetAll removes persistent mocks", func(t *testing.T) {
+		Catcher.ResetAll()
+		Catcher.NewMock().WithQuery("SELECT name FROM settings").Persist().WithReply([]map[string]interface{}{{"name": "base"}})
+		Catcher.ResetAll()
+		if name := getName("SELECT name FROM settings"); name != "" {
+			t.Errorf("Persistent mock should be removed by ResetAll. Got: [%v]", name)
+		}
+	})
+}
+
+func TestQueryFingerprint(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQueryFingerprint(`SELECT name FROM users WHERE id = 1 AND login = 'root'`).
+		WithReply([]map[string]interface{}{{"name": "FirstLast"}})
+
+	queries := []string{
+		`SELECT name FROM users WHERE id = 1 AND login = 'root'`,
+		`SELECT name FROM users WHERE id = 42 AND login = 'admin'`,
+		`SELECT name FROM users WHERE id = 3.5 AND login = 'it''s me'`,
+		`SELECT name FROM users WHERE id = ? AND login = ?`,
+	}
+	for _, query := range queries {
+		var name string
+		if err := db.QueryRow(query).Scan(&name); err != nil {
+			t.Fatalf("Query [%v] failed [%v]", query, err)
+		}
+		if name != "FirstLast" {
+			t.Errorf("Query [%v] not matched by fingerprint", query)
+		}
+	}
+
+	rows, _ := db.Query(`SELECT name FROM users WHERE age = 1 AND login = 'root'`)
+	defer rows.Close()
+	if rows.Next() {
+		t.Errorf("Query with other column should not be matched")
+	}
+}
+
+func TestTypedReplies(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+
+	t.Run("Ints", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery("SELECT id FROM users").WithReplyInts("id", []int64{1, 2, 3})
+		rows, err := db.Query("SELECT id FROM users")
+		if err != nil {
+			t.Fatalf("Query failed [%v]", err)
+		}
+		defer rows.Close()
+		var result []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				t.Fatalf("Scan failed [%v]", err)
+			}
+			result = append(result, id)
+		}
+		if len(result) != 3 || result[0] != 1 || result[2] != 3 {
+			t.Errorf("Ids mismatch. Got: [%v]", result)
+		}
+	})
+
+	t.Run("Floats", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery("SELECT price FROM goods").WithReplyFloats("price", []float64{1.5})
+		var price float64
+		if err := db.QueryRow("SELECT price FROM goods").Scan(&price); err != nil {
+			t.Fatalf("Query failed [%v]", err)
+		}
+		if price != 1.5 {
+			t.Errorf("Price mismatches. Expected: [%v] , Got: [%v]", 1.5, price)
+		}
+	})
+
+	t.Run("Strings", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery("SELECT name FROM users").WithReplyStrings("name", []string{"first", "second"})
+		rows, err := db.Query("SELECT name FROM users")
+		if err != nil {
+			t.Fatalf("Query failed [%v]", err)
+		}
+		defer rows.Close()
+		var result []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				t.Fatalf("Scan failed [%v]", err)
+			}
+			result = append(result, name)
+		}
+		if len(result) != 2 || result[0] != "first" || result[1] != "second" {
+			t.Errorf("Names mismatch. Got: [%v]", result)
+		}
+	})
+
+	t.Run("Bools", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery("SELECT active FROM users").WithReplyBools("active", []bool{true})
+		var active bool
+		if err := db.QueryRow("SELECT active FROM users").Scan(&active); err != nil {
+			t.Fatalf("Query failed [%v]", err)
+		}
+		if !active {
+			t.Errorf("Active mismatches. Expected: [%v] , Got: [%v]", true, active)
+		}
+	})
+}
+
+func TestQueriesHistory(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset()
+
+	if query := Catcher.LastQuery(); query != "" {
+		t.Errorf("LastQuery should be empty after Reset. Got: [%v]", query)
+	}
+
+	db.Exec("UPDATE users SET age = ? WHERE id = ?", 27, 1)
+	db.Query("SELECT name FROM users")
+
+	expected := []string{"UPDATE users SET age = ? WHERE id = ?", "SELECT name FROM users"}
+	if query := Catcher.LastQuery(); query != expected[1] {
+		t.Errorf("LastQuery mismatches. Expected: [%v] , Got: [%v]", expected[1], query)
+	}
+	queries := Catcher.Queries()
+	if len(queries) != len(expected) {
+		t.Fatalf("Queries count mismatches. Expected: [%v] , Got: [%v]", len(expected), len(queries))
+	}
+	for i := range expected {
+		if queries[i] != expected[i] {
+			t.Errorf("Query %d mismatches. Expected: [%v] , Got: [%v]", i, expected[i], queries[i])
+		}
+	}
+}
+
+func TestInsertReturning(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery(`INSERT INTO users`).WithID(64).WithRowsNum(1).
+		WithReply([]map[string]interface{}{{"id": int64(64)}})
+
+	var id int64
+	if err := db.QueryRow(`INSERT INTO users (name) VALUES (?) RETURNING id`, "FirstLast").Scan(&id); err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	if id != 64 {
+		t.Errorf("Returned id mismatches. Expected: [%v] , Got: [%v]", 64, id)
+	}
+
+	res, err := db.Exec(`INSERT INTO users (name) VALUES (?)`, "FirstLast")
+	if err != nil {
+		t.Fatalf("Exec failed [%v]", err)
+	}
+	if lastID, _ := res.LastInsertId(); lastID != 64 {
+		t.Errorf("Last insert id mismatches. Expected: [%v] , Got: [%v]", 64, lastID)
+	}
+}
+
+func TestMaxRows(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+
+	countRows := func() int {
+		rows, err := db.Query("SELECT id FROM users")
+		if err != nil {
+			t.Fatalf("Query failed [%v]", err)
+		}
+		defer rows.Close()
+		count := 0
+		for rows.Next() {
+			count++
+		}
+		return count
+	}
+
+	Catcher.Reset().NewMock().WithQuery("SELECT id FROM users").WithReplyInts("id", []int64{1, 2, 3, 4, 5}).WithMaxRows(2)
+	if count := countRows(); count != 2 {
+		t.Errorf("Rows count mismatches. Expected: [%v] , Got: [%v]", 2, count)
+	}
+
+	Catcher.Reset().NewMock().WithQuery("SELECT id FROM users").WithReplyInts("id", []int64{1, 2, 3}).WithMaxRows(10)
+	if count := countRows(); count != 3 {
+		t.Errorf("Rows count mismatches. Expected: [%v] , Got: [%v]", 3, count)
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	reply := []map[string]interface{}{{"age": int64(30)}, {"age": 18}, {"age": 45.5}, {"age": int64(27)}}
+
+	getAges := func() []float64 {
+		rows, err := db.Query("SELECT age FROM users")
+		if err != nil {
+			t.Fatalf("Query failed [%v]", err)
+		}
+		defer rows.Close()
+		var ages []float64
+		for rows.Next() {
+			var age float64
+			if err := rows.Scan(&age); err != nil {
+				t.Fatalf("Scan failed [%v]", err)
+			}
+			ages = append(ages, age)
+		}
+		return ages
+	}
+
+	t.Run("Ascending", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery("SELECT age FROM users").WithReply(reply).WithOrderBy("age", false)
+		expected := []float64{18, 27, 30, 45.5}
+		ages := getAges()
+		if !reflect.DeepEqual(ages, expected) {
+			t.Errorf("Order mismatches. Expected: [%v] , Got: [%v]", expected, ages)
+		}
+	})
+
+	t.Run("Descending", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery("SELECT age FROM users").WithReply(reply).WithOrderBy("age", true)
+		expected := []float64{45.5, 30, 27, 18}
+		ages := getAges()
+		if !reflect.DeepEqual(ages, expected) {
+			t.Errorf("Order mismatches. Expected: [%v] , Got: [%v]", expected, ages)
+		}
+	})
+
+	t.Run("Mixed types are grouped", func(t *testing.T) {
+		mixed := [][]driver.Value{{"b"}, {int64(2)}, {nil}, {"a"}, {int64(1)}}
+		sorted := sortRows(mixed, 0, false)
+		expected := []interface{}{nil, int64(1), int64(2), "a", "b"}
+		for i, row := range sorted {
+			if row[0] != expected[i] {
+				t.Errorf("Value %d mismatches. Expected: [%v] , Got: [%v]", i, expected[i], row[0])
+			}
+		}
+		if mixed[0][0] != "b" {
+			t.Errorf("Original rows should not be sorted")
+		}
+		if reply[0]["age"] != int64(30) {
+			t.Errorf("Original response should not be sorted")
+		}
+	})
+}
+
+func TestSnapshot(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT name FROM users").WithArgs("first").WithReply([]map[string]interface{}{{"name": "first"}})
+
+	snap := Catcher.Snapshot()
+
+	// Mutate original after snapshot
+	Catcher.Mocks[0].Args[0] = "changed"
+	Catcher.Mocks[0].Response[0]["name"] = "changed"
+	Catcher.NewMock().WithQuery("SELECT name FROM goods")
+
+	if len(snap.Mocks) != 1 {
+		t.Fatalf("Snapshot mocks count mismatches. Expected: [%v] , Got: [%v]", 1, len(snap.Mocks))
+	}
+	if snap.Mocks[0].Args[0] != "first" || snap.Mocks[0].Response[0]["name"] != "first" {
+		t.Errorf("Snapshot was affected by mutation of original. Got: [%v] [%v]", snap.Mocks[0].Args, snap.Mocks[0].Response)
+	}
+
+	Catcher.Restore(snap)
+	if len(Catcher.Mocks) != 1 {
+		t.Fatalf("Restored mocks count mismatches. Expected: [%v] , Got: [%v]", 1, len(Catcher.Mocks))
+	}
+	var name string
+	if err := db.QueryRow("SELECT name FROM users", "first").Scan(&name); err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	if name != "first" {
+		t.Errorf("Name mismatches. Expected: [%v] , Got: [%v]", "first", name)
+	}
+
+	// Restored mocks are independent from snapshot too
+	Catcher.Mocks[0].Response[0]["name"] = "changed"
+	if snap.Mocks[0].Response[0]["name"] != "first" {
+		t.Errorf("Snapshot was affected by mutation of restored mocks")
+	}
+}
+
+func TestMatchByOrdinal(t *testing.T) {
+	args := []driver.NamedValue{
+		{Ordinal: 3, Value: "c"},
+		{Ordinal: 1, Value: "a"},
+		{Ordinal: 2, Value: "b"},
+	}
+
+	fr := Catcher.Reset().NewMock().WithArgs("a", "b", "c")
+	if fr.IsMatch("", args) {
+		t.Errorf("Positional matching should fail for reordered args")
+	}
+
+	fr.MatchByOrdinal()
+	if !fr.IsMatch("", args) {
+		t.Errorf("Args should match by Ordinal")
+	}
+
+	if fr.IsMatch("", []driver.NamedValue{{Ordinal: 1, Value: "a"}, {Ordinal: 5, Value: "b"}, {Ordinal: 2, Value: "c"}}) {
+		t.Errorf("Out of range Ordinal should not match")
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	defer Catcher.ResetAll()
+
+	var traced []string
+	Catcher.ResetAll().NewMock().WithQuery("SELECT NAME FROM USERS").StrictMatch().WithReply([]map[string]interface{}{{"name": "FirstLast"}})
+	Catcher.Use(func(next Handler) Handler {
+		return func(query string, args []driver.NamedValue) *FakeResponse {
+			traced = append(traced, query)
+			return next(query, args)
+		}
+	})
+	Catcher.Use(func(next Handler) Handler {
+		return func(query string, args []driver.NamedValue) *FakeResponse {
+			return next(strings.ToUpper(query), args)
+		}
+	})
+
+	var name string
+	if err := db.QueryRow("select name from users").Scan(&name); err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	if name != "FirstLast" {
+		t.Errorf("Uppercased query not matched. Got: [%v]", name)
+	}
+	if len(traced) != 1 || traced[0] != "select name from users" {
+		t.Errorf("First interceptor should see original query. Got: [%v]", traced)
+	}
+	if query := Catcher.LastQuery(); query != "SELECT NAME FROM USERS" {
+		t.Errorf("FindResponse should receive rewritten query. Got: [%v]", query)
+	}
+}
+
+func TestNumericLooseMatch(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+
+	t.Run("int matches int64", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery("SELECT name FROM users").WithArgs(27).WithReply([]map[string]interface{}{{"name": "FirstLast"}})
+		rows, _ := db.Query("SELECT name FROM users WHERE age = ?", 27)
+		if rows.Next() {
+			t.Errorf("Strict types should not match int with int64")
+		}
+		rows.Close()
+
+		Catcher.Mocks[0].NumericLooseMatch()
+		var name string
+		if err := db.QueryRow("SELECT name FROM users WHERE age = ?", 27).Scan(&name); err != nil {
+			t.Fatalf("Query failed [%v]", err)
+		}
+		if name != "FirstLast" {
+			t.Errorf("Name mismatches. Expected: [%v] , Got: [%v]", "FirstLast", name)
+		}
+	})
+
+	t.Run("float32 matches float64", func(t *testing.T) {
+		fr := Catcher.Reset().NewMock().WithArgs(float32(0.1), int32(5)).NumericLooseMatch()
+		if !fr.IsMatch("", []driver.NamedValue{{Ordinal: 1, Value: float64(0.1)}, {Ordinal: 2, Value: int64(5)}}) {
+			t.Errorf("float32 should match float64 of the same value")
+		}
+		if fr.IsMatch("", []driver.NamedValue{{Ordinal: 1, Value: float64(0.2)}, {Ordinal: 2, Value: int64(5)}}) {
+			t.Errorf("Different values should not match")
+		}
+		if fr.IsMatch("", []driver.NamedValue{{Ordinal: 1, Value: "0.1"}, {Ordinal: 2, Value: int64(5)}}) {
+			t.Errorf("String should not match number")
+		}
+	})
+
+	t.Run("large integers are compared exactly", func(t *testing.T) {
+		fr := Catcher.Reset().NewMock().WithArgs(int64(9007199254740993)).NumericLooseMatch()
+		if fr.IsMatch("", []driver.NamedValue{{Ordinal: 1, Value: int64(9007199254740992)}}) {
+			t.Errorf("Distinct integers above 2^53 should not match")
+		}
+		if !fr.IsMatch("", []driver.NamedValue{{Ordinal: 1, Value: uint64(9007199254740993)}}) {
+			t.Errorf("uint64 should match int64 of the same value")
+		}
+	})
+}
+
+func TestCatcherString(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset()
+	Catcher.NewMock().WithQuery("SELECT name FROM users").StrictMatch().OneTime().WithReply(commonReplyRows(2))
+	Catcher.NewMock().WithQuery("UPDATE users").WithArgs(int64(27)).WithError(sql.ErrNoRows)
+	db.Exec("UPDATE users SET age = ?", 27)
+
+	dump := Catcher.String()
+	t.Log("\n" + dump)
+	lines := strings.Split(strings.TrimSpace(dump), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Dump lines mismatch. Expected: [%v] , Got: [%v]", 3, len(lines))
+	}
+	for _, field := range []string{`"SELECT name FROM users"`, "*", "2", "strict,once"} {
+		if !strings.Contains(lines[1], field) {
+			t.Errorf("First mock line [%v] does not contain [%v]", lines[1], field)
+		}
+	}
+	for _, field := range []string{`"UPDATE users"`, "[27]", "1", "error"} {
+		if !strings.Contains(lines[2], field) {
+			t.Errorf("Second mock line [%v] does not contain [%v]", lines[2], field)
+		}
+	}
+	if count := Catcher.Mocks[1].TriggeredCount(); count != 1 {
+		t.Errorf("Triggered count mismatches. Expected: [%v] , Got: [%v]", 1, count)
+	}
+}
+
+func commonReplyRows(n int) []map[string]interface{} {
+	reply := make([]map[string]interface{}, n)
+	for i := range reply {
+		reply[i] = map[string]interface{}{"name": "FirstLast"}
+	}
+	return reply
+}
+
+func TestNamedArgs(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+
+	type Audit struct {
+		UpdatedBy string `db:"updated_by"`
+	}
+	type User struct {
+		Audit
+		ID       int64  `db:"id"`
+		Login    string `db:"login,omitempty"`
+		Password string `db:"-"`
+		Age      int64
+	}
+	user := User{Audit: Audit{UpdatedBy: "admin"}, ID: 7, Login: "root", Password: "secret", Age: 30}
+
+	t.Run("Struct fields are mapped by db tags", func(t *testing.T) {
+		named := structToNamedArgs(&user)
+		expected := map[string]interface{}{"updated_by": "admin", "id": int64(7), "login": "root", "Age": int64(30)}
+		if !reflect.DeepEqual(named, expected) {
+			t.Errorf("Named args mismatch. Expected: [%v] , Got: [%v]", expected, named)
+		}
+	})
+
+	t.Run("Struct matches named parameters", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery("UPDATE users").WithArgsStruct(user).WithRowsNum(1)
+		res, err := db.Exec("UPDATE users SET login = @login WHERE id = @id", sql.Named("login", "root"), sql.Named("id", int64(7)))
+		if err != nil {
+			t.Fatalf("Exec failed [%v]", err)
+		}
+		if affected, _ := res.RowsAffected(); affected != 1 {
+			t.Errorf("Struct args not matched")
+		}
+
+		res, _ = db.Exec("UPDATE users SET login = @login WHERE id = @id", sql.Named("login", "root"), sql.Named("id", int64(8)))
+		if affected, _ := res.RowsAffected(); affected != 0 {
+			t.Errorf("Different id should not be matched")
+		}
+
+		res, _ = db.Exec("UPDATE users SET login = ? WHERE id = ?", "root", int64(7))
+		if affected, _ := res.RowsAffected(); affected != 0 {
+			t.Errorf("Positional args should not be matched")
+		}
+	})
+}
+
+func TestTableFilter(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT name FROM users WHERE id").WithTable([]map[string]interface{}{
+		{"id": 1, "name": "first"},
+		{"id": 2, "name": "second"},
+		{"id": 3, "name": "third"},
+	}).FilterBy("id")
+
+	for id, expected := range map[int64]string{1: "first", 3: "third"} {
+		result := queryMaps(t, db, "SELECT name FROM users WHERE id = ?", id)
+		if len(result) != 1 || result[0]["name"] != expected {
+			t.Errorf("Rows mismatch. Expected name: [%v] , Got: [%v]", expected, result)
+		}
+	}
+
+	if result := queryMaps(t, db, "SELECT name FROM users WHERE id = ?", 4); len(result) != 0 {
+		t.Errorf("Missing id should return no rows. Got: [%v]", result)
+	}
+}
+
+// queryMaps runs query and scans all rows into maps by column names
+func queryMaps(t *testing.T, db *sql.DB, query string, args ...interface{}) []map[string]interface{} {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	defer rows.Close()
+	columns, _ := rows.Columns()
+	var result []map[string]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			t.Fatalf("Scan failed [%v]", err)
+		}
+		record := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			record[col] = values[i]
+		}
+		result = append(result, record)
+	}
+	return result
+}
+
+func TestFallThroughAfter(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset()
+	Catcher.NewMock().WithQuery("SELECT name FROM users").FallThroughAfter(1).WithReply([]map[string]interface{}{{"name": "special"}})
+	Catcher.NewMock().WithQuery("SELECT name FROM users").WithReply([]map[string]interface{}{{"name": "common"}})
+
+	for _, expected := range []string{"special", "common", "common"} {
+		var name string
+		if err := db.QueryRow("SELECT name FROM users").Scan(&name); err != nil {
+			t.Fatalf("Query failed [%v]", err)
+		}
+		if name != expected {
+			t.Errorf("Name mismatches. Expected: [%v] , Got: [%v]", expected, name)
+		}
+	}
+	if count := Catcher.Mocks[0].TriggeredCount(); count != 1 {
+		t.Errorf("Special mock triggered count mismatches. Expected: [%v] , Got: [%v]", 1, count)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("INSERT INTO foo")
+
+	insertIDs := func() []int64 {
+		ids := make([]int64, 3)
+		for i := range ids {
+			ids[i] = InsertRecord(db)
+		}
+		return ids
+	}
+
+	first := insertIDs() // Without seed
+	Catcher.Seed(42)
+	second := insertIDs()
+	Catcher.Seed(42)
+	third := insertIDs()
+	Catcher.Seed(43)
+	fourth := insertIDs()
+
+	if !reflect.DeepEqual(second, third) {
+		t.Errorf("Sequences for the same seed mismatch. Got: [%v] and [%v]", second, third)
+	}
+	if reflect.DeepEqual(second, fourth) || reflect.DeepEqual(first, second) {
+		t.Errorf("Sequences for different seeds should differ")
+	}
+}
+
+func TestAllowTables(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().AllowTables([]string{"users"})
+	defer Catcher.AllowTables(nil)
+
+	if _, err := db.Query("SELECT name FROM users"); err != nil {
+		t.Errorf("Allowed table query failed [%v]", err)
+	}
+	if _, err := db.Exec("DELETE FROM orders WHERE id = ?", 1); err == nil {
+		t.Errorf("Disallowed table query should fail")
+	}
+	if errs := Catcher.TableErrors(); len(errs) != 1 || !strings.Contains(errs[0].Error(), `"orders"`) {
+		t.Errorf("Table errors mismatch. Got: [%v]", errs)
+	}
+}
+
+func TestWithAnyQuery(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithAnyQuery("SELECT name FROM users", "SELECT name FROM admins").
+		WithReply([]map[string]interface{}{{"name": "FirstLast"}})
+
+	for _, query := range []string{"SELECT name FROM users WHERE id = 1", "SELECT name FROM admins"} {
+		var name string
+		if err := db.QueryRow(query).Scan(&name); err != nil {
+			t.Fatalf("Query [%v] failed [%v]", query, err)
+		}
+		if name != "FirstLast" {
+			t.Errorf("Name mismatches. Expected: [%v] , Got: [%v]", "FirstLast", name)
+		}
+	}
+	if err := db.QueryRow("SELECT name FROM guests").Scan(new(string)); err != sql.ErrNoRows {
+		t.Errorf("Other query should not match. Got: [%v]", err)
+	}
+}
+
+func TestQueryDurations(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset()
+	Catcher.NewMock().WithQuery("SELECT name FROM users").WithDelay(30 * time.Millisecond)
+	Catcher.NewMock().WithQuery("UPDATE users").WithDelay(10 * time.Millisecond)
+
+	db.Exec("UPDATE users SET age = 1")
+	db.Query("SELECT name FROM users")
+	db.Query("SELECT id FROM goods")
+
+	history := Catcher.History()
+	if len(history) != 3 {
+		t.Fatalf("History length mismatches. Expected: [%v] , Got: [%v]", 3, len(history))
+	}
+	if history[0].Duration < 10*time.Millisecond || history[1].Duration < 30*time.Millisecond {
+		t.Errorf("Durations should include delays. Got: [%v] [%v]", history[0].Duration, history[1].Duration)
+	}
+	if history[2].Duration >= 10*time.Millisecond {
+		t.Errorf("Query without delay took too long. Got: [%v]", history[2].Duration)
+	}
+
+	query, duration := Catcher.SlowestQuery()
+	if query != "SELECT name FROM users" || duration < 30*time.Millisecond {
+		t.Errorf("Slowest query mismatches. Got: [%v] [%v]", query, duration)
+	}
+
+	t.Run("Delay respects context", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery("SELECT name FROM users").WithDelay(time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := db.QueryContext(ctx, "SELECT name FROM users"); err != context.DeadlineExceeded {
+			t.Errorf("Error mismatches. Expected: [%v] , Got: [%v]", context.DeadlineExceeded, err)
+		}
+	})
+}
+
+// fakeClock is Clock advanced by tests, waits fire when Advance passes their time
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waits   map[chan time.Time]time.Time
+	waiting chan struct{} // signaled on every After
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0), waits: make(map[chan time.Time]time.Time), waiting: make(chan struct{}, 10)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.waits[ch] = c.now.Add(d)
+	c.waiting <- struct{}{}
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	for ch, at := range c.waits {
+		if !at.After(c.now) {
+			ch <- c.now
+			delete(c.waits, ch)
+		}
+	}
+}
+
+func TestClock(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	clock := newFakeClock()
+	Catcher.Reset().Clock = clock
+	defer func() { Catcher.Clock = nil }()
+	Catcher.NewMock().WithQuery("SELECT name FROM users").WithDelay(time.Hour)
+
+	t.Run("Advance ends delay", func(t *testing.T) {
+		done := make(chan error)
+		go func() {
+			_, err := db.Query("SELECT name FROM users")
+			done <- err
+		}()
+		<-clock.waiting
+		clock.Advance(time.Hour)
+		if err := <-done; err != nil {
+			t.Fatalf("Query failed [%v]", err)
+		}
+		if duration := Catcher.History()[0].Duration; duration != time.Hour {
+			t.Errorf("Duration mismatches. Expected: [%v] , Got: [%v]", time.Hour, duration)
+		}
+	})
+
+	t.Run("Context times out before delay", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if _, err := db.QueryContext(ctx, "SELECT name FROM users"); err != context.DeadlineExceeded {
+			t.Errorf("Error mismatches. Expected: [%v] , Got: [%v]", context.DeadlineExceeded, err)
+		}
+		<-clock.waiting
+		if elapsed := time.Since(start); elapsed >= time.Second {
+			t.Errorf("Query should not wait for the delay. Got: [%v]", elapsed)
+		}
+	})
+}
+
+func TestBinaryArgs(t *testing.T) {
+	binary := func(v []byte) []driver.NamedValue {
+		return []driver.NamedValue{{Ordinal: 1, Value: v}}
+	}
+
+	fr := Catcher.Reset().NewMock().WithArgs([]byte{0xDE, 0xAD})
+	if !fr.IsMatch("", binary([]byte{0xDE, 0xAD})) {
+		t.Errorf("Equal bytes should match")
+	}
+	if fr.IsMatch("", binary([]byte{0xDE})) {
+		t.Errorf("Different bytes should not match")
+	}
+	if fr.IsMatch("", []driver.NamedValue{{Ordinal: 1, Value: "\xDE\xAD"}}) {
+		t.Errorf("String should not match bytes")
+	}
+
+	for _, expected := range [][]byte{nil, {}} {
+		fr := Catcher.Reset().NewMock().WithArgs(expected)
+		if !fr.IsMatch("", binary(nil)) || !fr.IsMatch("", binary([]byte{})) {
+			t.Errorf("Nil and empty bytes should match expected %#v", expected)
+		}
+	}
+}
+
+func TestRewriteQuery(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	placeholders := regexp.MustCompile(`\$\d+`)
+	Catcher.RewriteQuery = func(query string) string {
+		return placeholders.ReplaceAllString(query, "?")
+	}
+	defer func() { Catcher.RewriteQuery = nil }()
+	Catcher.Reset().NewMock().WithQuery("UPDATE users SET age = ? WHERE id = ?").StrictMatch().WithRowsNum(1)
+
+	for _, query := range []string{"UPDATE users SET age = ? WHERE id = ?", "UPDATE users SET age = $1 WHERE id = $2"} {
+		res, err := db.Exec(query, 27, 1)
+		if err != nil {
+			t.Fatalf("Exec failed [%v]", err)
+		}
+		if affected, _ := res.RowsAffected(); affected != 1 {
+			t.Errorf("Query [%v] not matched after rewrite", query)
+		}
+	}
+
+	closeErr := errors.New("statement close failed")
+	Catcher.Reset().NewMock().WithQuery("UPDATE users SET age = ? WHERE id = ?").StrictMatch().WithMaxStmtUses(1).WithStmtCloseError(closeErr)
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Connection failed [%v]", err)
+	}
+	defer conn.Close()
+	stmt, err := conn.PrepareContext(context.Background(), "UPDATE users SET age = $1 WHERE id = $2")
+	if err != nil {
+		t.Fatalf("Prepare failed [%v]", err)
+	}
+	stmt.Exec(27, 1)
+	if _, err := stmt.Exec(28, 1); err == nil {
+		t.Errorf("Statement prepared with rewritten query should expire after one use")
+	}
+	if err := stmt.Close(); err != closeErr {
+		t.Errorf("Close error mismatches. Expected: [%v] , Got: [%v]", closeErr, err)
+	}
+}
+
+func TestGeneratedRows(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT id, name FROM users").WithColumns("id", "name").
+		WithGeneratedRows(100, func(i int) map[string]interface{} {
+			return map[string]interface{}{"id": int64(i + 1), "name": fmt.Sprintf("user%d", i+1)}
+		})
+
+	rows, err := db.Query("SELECT id, name FROM users")
+	if err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	defer rows.Close()
+	count := 0
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatalf("Scan failed [%v]", err)
+		}
+		count++
+		if id != int64(count) || name != fmt.Sprintf("user%d", count) {
+			t.Errorf("Row %d mismatches. Got: [%v %v]", count, id, name)
+		}
+	}
+	if count != 100 {
+		t.Errorf("Rows count mismatches. Expected: [%v] , Got: [%v]", 100, count)
+	}
+}
+
+func TestWithResult(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("INSERT INTO users").WithResult(64, 3)
+
+	res, err := db.Exec("INSERT INTO users (name) VALUES (?), (?), (?)", "a", "b", "c")
+	if err != nil {
+		t.Fatalf("Exec failed [%v]", err)
+	}
+	if id, _ := res.LastInsertId(); id != 64 {
+		t.Errorf("Last insert id mismatches. Expected: [%v] , Got: [%v]", 64, id)
+	}
+	if affected, _ := res.RowsAffected(); affected != 3 {
+		t.Errorf("Rows affected mismatches. Expected: [%v] , Got: [%v]", 3, affected)
+	}
+}
+
+func TestNormalizePlaceholdersMatch(t *testing.T) {
+	fr := Catcher.Reset().NewMock().WithQuery("SELECT name FROM users WHERE id = $1").StrictMatch().NormalizePlaceholders()
+	for _, query := range []string{
+		"SELECT name FROM users WHERE id = ?",
+		"SELECT name FROM users WHERE id = $1",
+		"SELECT name FROM users WHERE id = :id",
+	} {
+		if !fr.IsMatch(query, nil) {
+			t.Errorf("Query [%v] not matched with normalized placeholders", query)
+		}
+	}
+	if fr.IsMatch("SELECT name FROM users WHERE id = 1", nil) {
+		t.Errorf("Literal value should not match placeholder")
+	}
+}
+
+func TestResetGroup(t *testing.T) {
+	Catcher.Reset()
+	billing := Catcher.NewMock().WithQuery("SELECT amount FROM invoices").WithGroup("billing")
+	Catcher.NewMock().WithQuery("SELECT total FROM payments").WithGroup("billing")
+	users := Catcher.NewMock().WithQuery("SELECT name FROM users").WithGroup("users")
+	common := Catcher.NewMock().WithQuery("SELECT 1")
+
+	Catcher.ResetGroup("billing")
+	if len(Catcher.Mocks) != 2 || Catcher.Mocks[0] != users || Catcher.Mocks[1] != common {
+		t.Errorf("Only billing mocks should be removed. Got: [%v]", Catcher.Mocks)
+	}
+	for _, fr := range Catcher.Mocks {
+		if fr == billing {
+			t.Errorf("Billing mock was not removed")
+		}
+	}
+
+	Catcher.ResetGroup("")
+	if len(Catcher.Mocks) != 2 {
+		t.Errorf("Empty group name should remove nothing. Got: [%v]", Catcher.Mocks)
+	}
+}
+
+func TestGetMock(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT name FROM users").WithReply([]map[string]interface{}{{"name": "Alice"}})
+
+	if Catcher.GetMock("SELECT missing") != nil {
+		t.Errorf("Unknown pattern should return nil")
+	}
+	fr := Catcher.GetMock("SELECT name FROM users")
+	if fr == nil {
+		t.Fatalf("Registered mock was not found")
+	}
+	fr.WithReply([]map[string]interface{}{{"name": "Bob"}})
+
+	var name string
+	if err := db.QueryRow("SELECT name FROM users").Scan(&name); err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	if name != "Bob" {
+		t.Errorf("Name mismatches. Expected: [%v] , Got: [%v]", "Bob", name)
+	}
+
+	// Mocks changed while looked up must not race, checked by go test -race
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			fr.WithQuery("SELECT name FROM users")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		Catcher.GetMock("SELECT name FROM users")
+	}
+	wg.Wait()
+}
+
+func TestRegexpArg(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	uuid := RegexpArg(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	Catcher.Reset().NewMock().WithQuery("INSERT INTO sessions").WithArgs(uuid, int64(42)).WithRowsNum(7)
+
+	res, err := db.Exec("INSERT INTO sessions (token, user_id) VALUES (?, ?)", "3f2b8c1e-9d4a-4c5e-8b7a-1e2d3c4b5a69", 42)
+	if err != nil {
+		t.Fatalf("Exec failed [%v]", err)
+	}
+	if affected, _ := res.RowsAffected(); affected != 7 {
+		t.Errorf("UUID shaped arg should match. Expected: [%v] , Got: [%v]", 7, affected)
+	}
+	res, _ = db.Exec("INSERT INTO sessions (token, user_id) VALUES (?, ?)", "not-a-uuid", 42)
+	if affected, _ := res.RowsAffected(); affected == 7 {
+		t.Errorf("Arg not matching regexp should not match the mock")
+	}
+	if uuid.Match(int64(42)) {
+		t.Errorf("Non string args should never match")
+	}
+}
+
+func TestCatcherJSON(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	mc := &MockCatcher{PanicOnEmptyResponse: true}
+	mc.NewMock().WithQuery("SELECT name FROM users").WithArgs(int64(1)).
+		WithReply([]map[string]interface{}{{"name": "FirstLast", "age": int64(30)}}).WithColumns("name", "age").OneTime()
+	mc.NewMock().WithQuery("UPDATE users").WithNamedArgs(map[string]interface{}{"score": 1.5}).WithRowsNum(2).WithGroup("users")
+	mc.NewMock().WithQuery("DELETE FROM users").WithError(errors.New("forbidden")).StrictMatch()
+	mc.NewMock().WithQuery("SELECT id FROM goods").WithRowErrors(map[int]error{1: errors.New("connection reset")}).
+		WithConvertError("id", errors.New("invalid id"))
+	data, err := json.Marshal(mc)
+	if err != nil {
+		t.Fatalf("Marshal failed [%v]", err)
+	}
+
+	loaded := &MockCatcher{}
+	if err := json.Unmarshal(data, loaded); err != nil {
+		t.Fatalf("Unmarshal failed [%v]", err)
+	}
+	if !loaded.PanicOnEmptyResponse || len(loaded.Mocks) != 4 {
+		t.Fatalf("Loaded catcher mismatches. Got: [%v]", loaded)
+	}
+	if !reflect.DeepEqual(loaded.Mocks[0].Args, []interface{}{int64(1)}) || !loaded.Mocks[0].Once {
+		t.Errorf("Args mismatch. Got: [%#v]", loaded.Mocks[0].Args)
+	}
+	if !reflect.DeepEqual(loaded.Mocks[1].NamedArgs, map[string]interface{}{"score": 1.5}) || loaded.Mocks[1].Group != "users" {
+		t.Errorf("Named args mismatch. Got: [%#v]", loaded.Mocks[1].NamedArgs)
+	}
+	if loaded.Mocks[2].Error == nil || loaded.Mocks[2].Error.Error() != "forbidden" || !loaded.Mocks[2].Strict {
+		t.Errorf("Error mismatches. Got: [%v]", loaded.Mocks[2].Error)
+	}
+	if err := loaded.Mocks[3].RowErrors[1]; err == nil || err.Error() != "connection reset" {
+		t.Errorf("Row errors mismatch. Got: [%v]", loaded.Mocks[3].RowErrors)
+	}
+	if err := loaded.Mocks[3].ConvertErrors["id"]; err == nil || err.Error() != "invalid id" {
+		t.Errorf("Convert errors mismatch. Got: [%v]", loaded.Mocks[3].ConvertErrors)
+	}
+	again, _ := json.Marshal(loaded)
+	if string(again) != string(data) {
+		t.Errorf("Round trip mismatches.\nExpected: [%s]\nGot: [%s]", data, again)
+	}
+
+	defer Catcher.Restore(Catcher.Snapshot())
+	json.Unmarshal(data, Catcher)
+	var name string
+	var age int64
+	if err := db.QueryRow("SELECT name FROM users WHERE id = ?", 1).Scan(&name, &age); err != nil || name != "FirstLast" || age != 30 {
+		t.Errorf("Loaded mock should reply. Got: [%v] [%v] [%v]", name, age, err)
+	}
+
+	mc.NewMock().WithQuery("SELECT").WithArgs(RegexpArg("^a"))
+	if _, err := json.Marshal(mc); err == nil {
+		t.Errorf("Mocks with argument matchers should fail to serialize")
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT name").WithReply([]map[string]interface{}{{"name": "FirstLast"}})
+	Catcher.Freeze()
+	defer Catcher.Unfreeze()
+
+	registers := map[string]func(){
+		"NewMock": func() { Catcher.NewMock() },
+		"Attach":  func() { Catcher.Attach([]*FakeResponse{{Pattern: "SELECT age"}}) },
+	}
+	for name, register := range registers {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s should panic while mocks are frozen", name)
+				}
+			}()
+			register()
+		}()
+	}
+	if len(Catcher.Mocks) != 1 {
+		t.Errorf("Mocks count mismatches. Expected: [%v] , Got: [%v]", 1, len(Catcher.Mocks))
+	}
+	var name string
+	if err := db.QueryRow("SELECT name FROM users").Scan(&name); err != nil || name != "FirstLast" {
+		t.Errorf("Frozen mocks should still reply. Got: [%v] [%v]", name, err)
+	}
+
+	Catcher.Unfreeze().NewMock().WithQuery("SELECT age")
+	if len(Catcher.Mocks) != 2 {
+		t.Errorf("Mocks should be registered after Unfreeze")
+	}
+}
+
+func TestArgsHash(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	payload := strings.Repeat("large payload ", 100)
+	hash := HashArgs([]driver.NamedValue{{Value: int64(1)}, {Value: payload}})
+	Catcher.Reset().NewMock().WithQuery("UPDATE documents").WithArgsHash(hash).WithRowsNum(5)
+
+	res, _ := db.Exec("UPDATE documents SET body = ? WHERE id = ?", int64(1), payload)
+	if affected, _ := res.RowsAffected(); affected != 5 {
+		t.Errorf("Args with precomputed hash should match. Expected: [%v] , Got: [%v]", 5, affected)
+	}
+	res, _ = db.Exec("UPDATE documents SET body = ? WHERE id = ?", int64(2), payload)
+	if affected, _ := res.RowsAffected(); affected == 5 {
+		t.Errorf("Args with other hash should not match")
+	}
+	if HashArgs([]driver.NamedValue{{Value: int64(1)}}) == HashArgs([]driver.NamedValue{{Value: "1"}}) {
+		t.Errorf("Hash should depend on arg types")
+	}
+}
+
+func TestFloatNear(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("UPDATE products").WithArgs(FloatNear(0.3, 1e-9)).WithRowsNum(4)
+
+	a, b := 0.1, 0.2
+	if a+b == 0.3 {
+		t.Fatalf("Computed float should differ from literal")
+	}
+	res, _ := db.Exec("UPDATE products SET price = ?", a+b)
+	if affected, _ := res.RowsAffected(); affected != 4 {
+		t.Errorf("Float within epsilon should match. Expected: [%v] , Got: [%v]", 4, affected)
+	}
+	res, _ = db.Exec("UPDATE products SET price = ?", 0.31)
+	if affected, _ := res.RowsAffected(); affected == 4 {
+		t.Errorf("Float out of epsilon should not match")
+	}
+	if FloatNear(3, 1).Match(int64(3)) || FloatNear(3, 1).Match("3") {
+		t.Errorf("Non float args should never match")
+	}
+}
+
+func TestStrictMatching(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT name").WithReply([]map[string]interface{}{{"name": "FirstLast"}})
+	Catcher.StrictMatching = true
+	defer func() { Catcher.StrictMatching = false }()
+
+	var name string
+	if err := db.QueryRow("SELECT name FROM users").Scan(&name); err != nil || name != "FirstLast" {
+		t.Errorf("Matched query should reply. Got: [%v] [%v]", name, err)
+	}
+	_, err := db.Query("SELECT age FROM users WHERE id = ?", 1)
+	var noMatch *NoMatchError
+	if !errors.As(err, &noMatch) {
+		t.Fatalf("Missed query should fail with NoMatchError. Got: [%v]", err)
+	}
+	if noMatch.Query != "SELECT age FROM users WHERE id = 1" || len(noMatch.Args) != 1 {
+		t.Errorf("NoMatchError mismatches. Got: [%v] [%v]", noMatch.Query, noMatch.Args)
+	}
+	if _, err := db.Exec("DELETE FROM users"); !errors.As(err, &noMatch) {
+		t.Errorf("Missed exec should fail with NoMatchError. Got: [%v]", err)
+	}
+}
+
+func TestMatchPlaceholderCount(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("INSERT INTO t VALUES (?, ?, ?)").MatchPlaceholderCount().WithRowsNum(3)
+
+	res, _ := db.Exec("INSERT INTO t VALUES (?, ?, ?)", 1, 2, 3)
+	if affected, _ := res.RowsAffected(); affected != 3 {
+		t.Errorf("Insert with 3 placeholders should match. Expected: [%v] , Got: [%v]", 3, affected)
+	}
+	res, _ = db.Exec("INSERT INTO t VALUES (?, ?, ?, ?)", 1, 2, 3, 4)
+	if affected, _ := res.RowsAffected(); affected == 3 {
+		t.Errorf("Insert with 4 placeholders should not match")
+	}
+}
+
+func TestMeta(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	var scenario interface{}
+	Catcher.Reset()
+	Catcher.Register(Mock().WithQuery("UPDATE users").WithMeta(map[string]interface{}{"scenario": "rename"}).
+		WithMetaCallback(func(query string, args []driver.NamedValue, meta map[string]interface{}) {
+			scenario = meta["scenario"]
+		}))
+
+	db.Exec("UPDATE users SET name = ?", "FirstLast")
+	db.Exec("DELETE FROM users")
+	if scenario != "rename" {
+		t.Errorf("Callback meta mismatches. Expected: [%v] , Got: [%v]", "rename", scenario)
+	}
+	history := Catcher.History()
+	if len(history) != 2 {
+		t.Fatalf("History length mismatches. Expected: [%v] , Got: [%v]", 2, len(history))
+	}
+	if history[0].Meta["scenario"] != "rename" {
+		t.Errorf("History meta mismatches. Expected: [%v] , Got: [%v]", "rename", history[0].Meta)
+	}
+	if history[1].Meta != nil {
+		t.Errorf("Unmatched query should have no meta. Got: [%v]", history[1].Meta)
+	}
+}
+
+func TestArgTypes(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("UPDATE users").WithArgTypes(reflect.TypeOf(int64(0)), reflect.TypeOf("")).WithRowsNum(2)
+
+	res, _ := db.Exec("UPDATE users SET age = ? WHERE name = ?", 27, "FirstLast")
+	if affected, _ := res.RowsAffected(); affected != 2 {
+		t.Errorf("Args of expected types should match. Expected: [%v] , Got: [%v]", 2, affected)
+	}
+	res, _ = db.Exec("UPDATE users SET age = ? WHERE name = ?", "27", "FirstLast")
+	if affected, _ := res.RowsAffected(); affected == 2 {
+		t.Errorf("String arg should not match int64 type")
+	}
+}
+
+func TestFirstArg(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT plan").WithFirstArg(int64(1)).WithReply([]map[string]interface{}{{"plan": "free"}})
+	Catcher.NewMock().WithQuery("SELECT plan").WithFirstArg(int64(2)).WithReply([]map[string]interface{}{{"plan": "pro"}})
+	Catcher.NewMock().WithQuery("SELECT plan").WithFirstArg(int64(3)).WithReply([]map[string]interface{}{{"plan": "none"}})
+
+	for tenant, expected := range map[int64]string{1: "free", 2: "pro"} {
+		for _, rest := range [][]interface{}{{}, {"a"}, {"b", 42}} {
+			var plan string
+			args := append([]interface{}{tenant}, rest...)
+			if err := db.QueryRow("SELECT plan FROM tenants", args...).Scan(&plan); err != nil || plan != expected {
+				t.Errorf("Tenant %d with %v: plan mismatches. Expected: [%v] , Got: [%v] [%v]", tenant, rest, expected, plan, err)
+			}
+		}
+	}
+	var plan string
+	if err := db.QueryRow("SELECT plan FROM tenants").Scan(&plan); err != sql.ErrNoRows {
+		t.Errorf("Query without args should not match. Got: [%v] [%v]", plan, err)
+	}
+}
+
+func TestNotices(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("DROP TABLE").WithNotices([]string{"table \"users\" does not exist, skipping"})
+	Catcher.NewMock().WithQuery("VACUUM").WithNotices([]string{"vacuuming users", "vacuum done"})
+
+	db.Exec("DROP TABLE IF EXISTS users")
+	db.Exec("UPDATE users SET age = 1")
+	db.Exec("VACUUM users")
+	expected := []string{"table \"users\" does not exist, skipping", "vacuuming users", "vacuum done"}
+	if !reflect.DeepEqual(Catcher.Notices(), expected) {
+		t.Errorf("Notices mismatch. Expected: [%v] , Got: [%v]", expected, Catcher.Notices())
+	}
+	if history := Catcher.History(); len(history[2].Notices) != 2 {
+		t.Errorf("History notices mismatch. Got: [%v]", history[2].Notices)
+	}
+	if Catcher.Reset(); len(Catcher.Notices()) != 0 {
+		t.Errorf("Reset should clear notices. Got: [%v]", Catcher.Notices())
+	}
+}
+
+func TestArgTransform(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	lower := func(args []driver.NamedValue) []driver.NamedValue {
+		for i, arg := range args {
+			if s, ok := arg.Value.(string); ok {
+				args[i].Value = strings.ToLower(s)
+			}
+		}
+		return args
+	}
+	fr := Catcher.Reset().NewMock().WithQuery("SELECT id FROM users").WithArgs("first@example.com").
+		WithArgTransform(lower).CaptureArgs().WithReply([]map[string]interface{}{{"id": int64(1)}})
+
+	var id int64
+	if err := db.QueryRow("SELECT id FROM users WHERE email = ?", "First@Example.COM").Scan(&id); err != nil || id != 1 {
+		t.Errorf("Transformed arg should match. Got: [%v] [%v]", id, err)
+	}
+	if err := fr.AssertArgAt(0, 0, "First@Example.COM"); err != nil {
+		t.Errorf("Transform should not change captured args: %v", err)
+	}
+}
+
+func TestNilArgs(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	var nilName *string
+	name := "FirstLast"
+	Catcher.Reset().NewMock().WithQuery("UPDATE users SET name").WithArgs(nil).WithRowsNum(1)
+	Catcher.NewMock().WithQuery("UPDATE users SET nick").WithArgs(nilName).WithRowsNum(2)
+
+	for _, tc := range []struct {
+		query    string
+		arg      interface{}
+		affected int64
+	}{
+		{"UPDATE users SET name = ?", nil, 1},
+		{"UPDATE users SET name = ?", nilName, 1},
+		{"UPDATE users SET name = ?", &name, 0},
+		{"UPDATE users SET name = ?", "", 0},
+		{"UPDATE users SET nick = ?", nil, 2},
+		{"UPDATE users SET nick = ?", nilName, 2},
+		{"UPDATE users SET nick = ?", &name, 0},
+	} {
+		res, _ := db.Exec(tc.query, tc.arg)
+		if affected, _ := res.RowsAffected(); affected != tc.affected {
+			t.Errorf("%s with %#v: rows affected mismatches. Expected: [%v] , Got: [%v]", tc.query, tc.arg, tc.affected, affected)
+		}
+	}
+
+	typedNil := []driver.NamedValue{{Ordinal: 1, Value: nilName}}
+	if resp := Catcher.FindResponse("UPDATE users SET name = ?", typedNil); resp.RowsAffected == 1 {
+		t.Errorf("Untyped nil should not match typed nil pointer arg")
+	}
+	if resp := Catcher.FindResponse("UPDATE users SET nick = ?", typedNil); resp.RowsAffected != 2 {
+		t.Errorf("Typed nil pointer should match nil pointer of the same type")
+	}
+}
+
+func priorityMocks(n int) []*FakeResponse {
+	rnd := rand.New(rand.NewSource(1))
+	mocks := make([]*FakeResponse, n)
+	for i := range mocks {
+		mocks[i] = (&FakeResponse{Pattern: "SELECT", Exceptions: &Exceptions{}}).WithPriority(rnd.Intn(5)).WithRowsNum(int64(i))
+	}
+	return mocks
+}
+
+func TestAttachSorted(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	first, second := priorityMocks(50), priorityMocks(50)[:30]
+	Catcher.Reset()
+	Catcher.AttachSorted(first)
+	Catcher.AttachSorted(second)
+
+	expected := append(append([]*FakeResponse(nil), first...), second...)
+	sort.SliceStable(expected, func(i, j int) bool { return expected[i].Priority > expected[j].Priority })
+	if !reflect.DeepEqual(Catcher.Mocks, expected) {
+		t.Errorf("Mocks order should equal stable sort by priority")
+	}
+
+	high := Catcher.NewMock().WithQuery("UPDATE").WithPriority(10).WithRowsNum(100)
+	Catcher.NewMock().WithQuery("UPDATE").WithRowsNum(200)
+	Catcher.Mocks[len(Catcher.Mocks)-1], Catcher.Mocks[len(Catcher.Mocks)-2] = Catcher.Mocks[len(Catcher.Mocks)-2], Catcher.Mocks[len(Catcher.Mocks)-1]
+	res, _ := db.Exec("UPDATE users SET age = 1")
+	if affected, _ := res.RowsAffected(); affected != 100 {
+		t.Errorf("Mock with highest priority should match. Expected: [%v] , Got: [%v]", 100, affected)
+	}
+	if Catcher.Mocks[0] != high {
+		t.Errorf("Mocks should be ordered by priority after query")
+	}
+}
+
+func BenchmarkAttachSorted(b *testing.B) {
+	mocks := priorityMocks(5000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		mc := &MockCatcher{}
+		for start := 0; start < len(mocks); start += 500 {
+			mc.AttachSorted(mocks[start : start+500])
+		}
+	}
+}
+
+func TestQueryQuota(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	fr := Catcher.Reset().NewMock().WithQuery("SELECT name").WithReply([]map[string]interface{}{{"name": "FirstLast"}})
+	Catcher.QueryQuota = 2
+	defer func() { Catcher.QueryQuota = 0 }()
+
+	if Catcher.RemainingQuota() != 2 {
+		t.Errorf("Remaining quota mismatches. Expected: [%v] , Got: [%v]", 2, Catcher.RemainingQuota())
+	}
+	var name string
+	for i := 0; i < 2; i++ {
+		if err := db.QueryRow("SELECT name FROM users").Scan(&name); err != nil {
+			t.Errorf("Query %d within quota failed [%v]", i, err)
+		}
+	}
+	if _, err := db.Exec("UPDATE users SET age = 1"); err != ErrQuotaExceeded {
+		t.Errorf("Query over quota mismatches. Expected: [%v] , Got: [%v]", ErrQuotaExceeded, err)
+	}
+	if Catcher.RemainingQuota() != 0 {
+		t.Errorf("Remaining quota mismatches. Expected: [%v] , Got: [%v]", 0, Catcher.RemainingQuota())
+	}
+
+	Catcher.ResetState()
+	if fr.TriggeredCount() != 0 || len(Catcher.Mocks) != 1 {
+		t.Errorf("ResetState should clear counts and keep mocks")
+	}
+	if err := db.QueryRow("SELECT name FROM users").Scan(&name); err != nil || name != "FirstLast" {
+		t.Errorf("Query after ResetState should succeed. Got: [%v] [%v]", name, err)
+	}
+
+	db.QueryRow("SELECT name FROM users").Scan(&name)
+	Catcher.ResetAll()
+	if Catcher.RemainingQuota() != 2 {
+		t.Errorf("Remaining quota after ResetAll mismatches. Expected: [%v] , Got: [%v]", 2, Catcher.RemainingQuota())
+	}
+	if _, err := db.Exec("UPDATE users SET age = 1"); err != nil {
+		t.Errorf("Query after ResetAll should succeed. Got: [%v]", err)
+	}
+}
+
+func TestDependsOn(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	insert := Catcher.Reset().NewMock().WithQuery("INSERT INTO users").WithID(1)
+	Catcher.NewMock().WithQuery("SELECT name FROM users").DependsOn(insert).WithReply([]map[string]interface{}{{"name": "FirstLast"}})
+
+	var name string
+	if err := db.QueryRow("SELECT name FROM users WHERE id = ?", 1).Scan(&name); err != sql.ErrNoRows {
+		t.Errorf("Dependent mock should not match before dependency. Got: [%v] [%v]", name, err)
+	}
+	db.Exec("INSERT INTO users (name) VALUES (?)", "FirstLast")
+	if err := db.QueryRow("SELECT name FROM users WHERE id = ?", 1).Scan(&name); err != nil || name != "FirstLast" {
+		t.Errorf("Dependent mock should match after dependency. Got: [%v] [%v]", name, err)
+	}
+
+	snap := Catcher.Snapshot()
+	if snap.Mocks[1].Dependency != snap.Mocks[0] {
+		t.Errorf("Snapshot dependency should point to cloned mock")
+	}
+}
+
+func TestActiveBetween(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT name FROM users").ActiveBetween(1, 3).WithReply([]map[string]interface{}{{"name": "FirstLast"}})
+
+	for i, expected := range []bool{false, true, true, false} {
+		var name string
+		err := db.QueryRow("SELECT name FROM users").Scan(&name)
+		if matched := err == nil && name == "FirstLast"; matched != expected {
+			t.Errorf("Match of query %v mismatches. Expected: [%v] , Got: [%v]", i, expected, matched)
+		}
+	}
+}
+
+func TestReplyError(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	errUnique := errors.New("duplicate key value violates unique constraint")
+
+	for _, replyErr := range []error{errUnique, driver.ErrBadConn} {
+		Catcher.Reset().NewMock().WithQuery("INSERT INTO users").WithReplyError(replyErr).WithExecException()
+		_, err := db.Exec("INSERT INTO users (name) VALUES (?)", "FirstLast")
+		if err == nil || err.Error() != replyErr.Error() || errors.Is(err, driver.ErrBadConn) {
+			t.Errorf("Error mismatches. Expected: [%v] , Got: [%v]", replyErr, err)
+		}
+		if len(Catcher.History()) != 1 {
+			t.Errorf("Query should not be retried. Got: [%v] attempts", len(Catcher.History()))
+		}
+	}
+}
+
+func TestStatementInBatch(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithStatementInBatch("UPDATE accounts SET balance").StrictMatch().WithRowsNum(2)
+
+	res, err := db.Exec("INSERT INTO audit (note) VALUES ('move; funds'); UPDATE accounts SET balance")
+	if err != nil {
+		t.Fatalf("Exec failed [%v]", err)
+	}
+	if affected, _ := res.RowsAffected(); affected != 2 {
+		t.Errorf("Rows affected mismatches. Expected: [%v] , Got: [%v]", 2, affected)
+	}
+	if _, err := db.Exec("INSERT INTO audit (note) VALUES ('UPDATE accounts SET balance')"); err != nil || Catcher.History()[1].Matched {
+		t.Errorf("Statement inside string literal should not match [%v]", err)
+	}
+
+	Catcher.Reset().NewMock().WithStatementInBatch("INSERT INTO t").WithID(5)
+	res, err = db.Exec("CREATE TABLE t (id int); INSERT INTO t VALUES (1)")
+	if err != nil {
+		t.Fatalf("Exec of batch starting with DDL failed [%v]", err)
+	}
+	if id, _ := res.LastInsertId(); id != 5 {
+		t.Errorf("Last insert id mismatches. Expected: [%v] , Got: [%v]", 5, id)
+	}
+}
+
+func TestOnExhausted(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	var exhausted []*FakeResponse
+	hook := func(fr *FakeResponse) {
+		exhausted = append(exhausted, fr)
+		Catcher.NewMock().WithQuery("SELECT name FROM users").WithReply([]map[string]interface{}{{"name": "next phase"}})
+	}
+	limited := Catcher.Reset().NewMock().WithQuery("SELECT name FROM users").FallThroughAfter(2).OnExhausted(hook).
+		WithReply([]map[string]interface{}{{"name": "first phase"}})
+
+	for i, expected := range []string{"first phase", "first phase", "next phase", "next phase"} {
+		var name string
+		if err := db.QueryRow("SELECT name FROM users").Scan(&name); err != nil || name != expected {
+			t.Errorf("Query %d mismatches. Expected: [%v] , Got: [%v] [%v]", i, expected, name, err)
+		}
+		if fired := len(exhausted); (i < 1 && fired != 0) || (i >= 1 && fired != 1) {
+			t.Errorf("Hook after query %d fired %d times", i, fired)
+		}
+	}
+	if len(exhausted) != 1 || exhausted[0] != limited {
+		t.Errorf("Hook should receive exhausted mock")
+	}
+
+	once := Catcher.Reset().NewMock().OneTime().OnExhausted(func(fr *FakeResponse) { exhausted = append(exhausted, fr) })
+	once.MarkAsTriggered()
+	once.MarkAsTriggered()
+	if len(exhausted) != 2 || exhausted[1] != once {
+		t.Errorf("Hook of OneTime mock should fire once. Got: [%v] calls", len(exhausted)-1)
+	}
+}
+
+func TestRoundRobinReplies(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT host FROM replicas").WithRoundRobinReplies(
+		[]map[string]interface{}{{"host": "a"}},
+		[]map[string]interface{}{{"host": "b"}},
+		[]map[string]interface{}{{"host": "c"}},
+	)
+
+	query := func() string {
+		var host string
+		if err := db.QueryRow("SELECT host FROM replicas").Scan(&host); err != nil {
+			t.Fatalf("Query failed [%v]", err)
+		}
+		return host
+	}
+	var hosts []string
+	for i := 0; i < 7; i++ {
+		hosts = append(hosts, query())
+	}
+	if expected := []string{"a", "b", "c", "a", "b", "c", "a"}; !reflect.DeepEqual(hosts, expected) {
+		t.Errorf("Hosts mismatch. Expected: [%v] , Got: [%v]", expected, hosts)
+	}
+
+	Catcher.ResetState()
+	if host := query(); host != "a" {
+		t.Errorf("Host after ResetState mismatches. Expected: [%v] , Got: [%v]", "a", host)
+	}
+
+	Catcher.Restore(Catcher.Snapshot())
+	if host := query(); host != "b" {
+		t.Errorf("Host after Restore mismatches. Expected: [%v] , Got: [%v]", "b", host)
+	}
+}
+
+func TestDialect(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	defer func() { Catcher.Dialect = "" }()
+
+	Catcher.Reset().Dialect = DialectPostgres
+	Catcher.NewMock().WithQuery("UPDATE users SET name = ? WHERE id = ?").StrictMatch().WithRowsNum(1)
+	res, err := db.Exec(`update "users" SET "name" = $1 WHERE "id" = $2`, "FirstLast", 1)
+	if err != nil {
+		t.Fatalf("Exec failed [%v]", err)
+	}
+	if affected, _ := res.RowsAffected(); affected != 1 {
+		t.Errorf("Postgres query should match. Rows affected: [%v]", affected)
+	}
+
+	Catcher.Reset().Dialect = DialectMySQL
+	Catcher.NewMock().WithQuery("SELECT name FROM users").WithReply([]map[string]interface{}{{"name": "FirstLast"}})
+	var name string
+	if err := db.QueryRow("SELECT `name` FROM `users`").Scan(&name); err != nil || name != "FirstLast" {
+		t.Errorf("MySQL query should match. Got: [%v] [%v]", name, err)
+	}
+}
+
+// temporaryError is a custom driver error classified by Temporary() method
+type temporaryError struct {
+	code string
+}
+
+func (e *temporaryError) Error() string {
+	return "temporary failure " + e.code
+}
+
+func (e *temporaryError) Temporary() bool {
+	return true
+}
+
+func TestCustomErrorType(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset()
+	Catcher.NewMock().WithQuery("SELECT name FROM users").WithError(&temporaryError{code: "40001"})
+	Catcher.NewMock().WithQuery("UPDATE users").WithError(fmt.Errorf("update: %w", &temporaryError{code: "40P01"}))
+
+	var temporary interface{ Temporary() bool }
+	if err := db.QueryRow("SELECT name FROM users").Scan(new(string)); !errors.As(err, &temporary) || !temporary.Temporary() {
+		t.Errorf("Query error should implement Temporary. Got: [%v]", err)
+	}
+	var custom *temporaryError
+	if _, err := db.Exec("UPDATE users SET name = ?", "FirstLast"); !errors.As(err, &custom) || custom.code != "40P01" {
+		t.Errorf("Exec error should unwrap to custom type. Got: [%v]", err)
+	}
+}
+
+func TestWithoutQuerySubstring(t *testing.T) {
+	fr := Catcher.Reset().NewMock().WithQuery("SELECT * FROM users").WithoutQuerySubstring("FOR UPDATE", "NOWAIT")
+
+	if !fr.IsMatch("SELECT * FROM users WHERE id = 1", nil) {
+		t.Errorf("Query without forbidden substrings should match")
+	}
+	for _, query := range []string{"SELECT * FROM users WHERE id = 1 FOR UPDATE", "SELECT * FROM users NOWAIT"} {
+		if fr.IsMatch(query, nil) {
+			t.Errorf("Query with forbidden substring should not match [%v]", query)
+		}
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	Catcher.Reset()
+	Catcher.NewMock().WithQuery("SELECT name FROM users").OneTime().MarkAsTriggered()
+	Catcher.NewMock().WithQuery("SELECT name FROM guests")
+	Catcher.NewMock().WithQuery("SELECT name FROM users").WithArgs(int64(2))
+	Catcher.NewMock().WithQuery("SELECT name FROM users").WithArgs(int64(1))
+	Catcher.NewMock().WithQuery("SELECT name")
+
+	candidates := Catcher.Candidates("SELECT name FROM users WHERE id = ?", []driver.NamedValue{{Ordinal: 1, Value: int64(1)}})
+	expected := []string{
+		"exhausted by OneTime or FallThroughAfter",
+		"query does not match pattern",
+		"args do not match",
+		"matches",
+		"matches, but mock 3 is checked first",
+	}
+	if len(candidates) != len(expected) {
+		t.Fatalf("Candidates count mismatches. Expected: [%v] , Got: [%v]", len(expected), len(candidates))
+	}
+	for i, candidate := range candidates {
+		if candidate.Reason != expected[i] || candidate.Matched != (i >= 3) || candidate.Mock != Catcher.Mocks[i] {
+			t.Errorf("Candidate %d mismatches. Expected: [%v] , Got: [%v] [%v]", i, expected[i], candidate.Reason, candidate.Matched)
+		}
+	}
+}
+
+func TestQueryRegexp(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	fr := Catcher.Reset().NewMock().WithQuery("never used").WithQueryRegexp(`^SELECT name FROM (users|guests) WHERE id = \d+$`).
+		WithReply([]map[string]interface{}{{"name": "FirstLast"}})
+
+	for query, expected := range map[string]bool{
+		"SELECT name FROM users WHERE id = 1":   true,
+		"SELECT name FROM guests WHERE id = 20": true,
+		"SELECT name FROM users WHERE id = ?":   false,
+		"SELECT name FROM admins WHERE id = 1":  false,
+	} {
+		if matched := fr.IsMatch(query, nil); matched != expected {
+			t.Errorf("Match of [%v] mismatches. Expected: [%v] , Got: [%v]", query, expected, matched)
+		}
+	}
+	var name string
+	if err := db.QueryRow("SELECT name FROM guests WHERE id = ?", 7).Scan(&name); err != nil || name != "FirstLast" {
+		t.Errorf("Query should match regexp. Got: [%v] [%v]", name, err)
+	}
+
+	data, err := json.Marshal(Catcher)
+	if err != nil {
+		t.Fatalf("Marshal failed [%v]", err)
+	}
+	loaded := &MockCatcher{}
+	if err := json.Unmarshal(data, loaded); err != nil {
+		t.Fatalf("Unmarshal failed [%v]", err)
+	}
+	if !loaded.Mocks[0].IsMatch("SELECT name FROM users WHERE id = 1", nil) {
+		t.Errorf("Loaded mock should keep its regexp")
+	}
+
+	Catcher.Reset().NewMock().WithQueryRegexp(`^SELECT .* FROM users`).WithReply([]map[string]interface{}{{"id": int64(1)}})
+	Catcher.Dialect = DialectPostgres
+	defer func() { Catcher.Dialect = "" }()
+	var id int64
+	if err := db.QueryRow("SELECT id FROM users").Scan(&id); err != nil || id != 1 {
+		t.Errorf("Regexp should match the raw query regardless of the dialect. Got: [%v] [%v]", id, err)
+	}
+}
+
+func BenchmarkQueryRegexp(b *testing.B) {
+	mc := &MockCatcher{}
+	for i := 0; i < 200; i++ {
+		mc.NewMock().WithQueryRegexp(fmt.Sprintf(`^SELECT \* FROM table_%d WHERE id = \d+$`, i))
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		mc.FindResponse("SELECT * FROM table_199 WHERE id = 1", nil)
+		if i%1000 == 0 {
+			mc.ResetState() // keeps history small
+		}
+	}
+}
+
+func TestErrorOnAmbiguous(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().ErrorOnAmbiguous = true
+	defer func() { Catcher.ErrorOnAmbiguous = false }()
+	Catcher.NewMock().WithQuery("SELECT name FROM users").WithReply([]map[string]interface{}{{"name": "users"}})
+	Catcher.NewMock().WithQuery("SELECT name").WithReply([]map[string]interface{}{{"name": "any"}})
+	Catcher.NewMock().WithQuery("SELECT name FROM guests").OneTime().MarkAsTriggered()
+	Catcher.NewMock().WithQuery("SELECT name FROM guests")
+
+	var ambiguous *AmbiguousMatchError
+	if err := db.QueryRow("SELECT name FROM users").Scan(new(string)); !errors.As(err, &ambiguous) || len(ambiguous.Mocks) != 2 {
+		t.Errorf("Overlapping mocks should fail the query. Got: [%v]", err)
+	}
+	if err := db.QueryRow("SELECT name FROM guests").Scan(new(string)); !errors.As(err, &ambiguous) || len(ambiguous.Mocks) != 2 {
+		t.Errorf("Exhausted mock should not count as overlapping. Got: [%v]", err)
+	}
+
+	Catcher.Mocks[0].WithPriority(1)
+	var name string
+	if err := db.QueryRow("SELECT name FROM users").Scan(&name); err != nil || name != "users" {
+		t.Errorf("Priority should resolve the ambiguity. Got: [%v] [%v]", name, err)
+	}
+
+	Catcher.Reset().NewMock().WithQuery("SELECT name FROM users").WithReply([]map[string]interface{}{{"name": "general"}})
+	Catcher.NewMock().WithQuery("SELECT name FROM users").OnCall(2).WithReply([]map[string]interface{}{{"name": "second"}})
+	for i, expected := range []string{"general", "second", "general"} {
+		if err := db.QueryRow("SELECT name FROM users").Scan(&name); err != nil || name != expected {
+			t.Errorf("OnCall mock should win over general one on call %d. Expected: [%v] , Got: [%v] [%v]", i+1, expected, name, err)
+		}
+	}
+}
@@ -1,26 +1,42 @@
 package gomocket
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"errors"
 	"io"
+	"log"
 	"reflect"
+	"strings"
 	"time"
 )
 
 // RowsCursor is implementation of Rows sql interface
 type RowsCursor struct {
-	cols    []string
-	colType [][]string
-	posSet  int
-	posRow  int
-	rows    [][]*row
-	closed  bool
+	cols     []string
+	colType  [][]string       // Database type names of columns per result set
+	scanType [][]reflect.Type // Overrides of scan types per result set, nil means derived from colType
+	posSet   int
+	posRow   int
+	rows     [][]*row
+	closed   bool
 
 	// errPos and err are for making Next return early with error.
 	errPos int
 	err    error
+	// rowErrors are returned by Next instead of rows at their index.
+	rowErrors map[int]error
+	// stream emits rows instead of rows until closed, waiting is interrupted by ctx.
+	stream <-chan map[string]interface{}
+	ctx    context.Context
+	// encoders transform streamed and lazy values as they are emitted, validate logs values database/sql can not scan.
+	encoders map[string]func(interface{}) driver.Value
+	validate bool
+	// convertErrors are returned by scanning of columns instead of their values.
+	convertErrors map[string]error
+	// current keeps values of the row NextRow advanced to.
+	current []driver.Value
 
 	bytesClone map[*byte][]byte
 }
@@ -47,12 +63,26 @@ func (rc *RowsCursor) Columns() []string {
 
 // ColumnTypeScanType may be implemented by Rows. It should return
 // the value type that can be used to scan types into.
+// Columns without declared type are scanned into interface{}.
 func (rc *RowsCursor) ColumnTypeScanType(index int) reflect.Type {
-	return colTypeToReflectType(rc.colType[rc.posSet][index])
+	if rc.posSet < len(rc.scanType) && index < len(rc.scanType[rc.posSet]) && rc.scanType[rc.posSet][index] != nil {
+		return rc.scanType[rc.posSet][index]
+	}
+	return colTypeToReflectType(rc.ColumnTypeDatabaseTypeName(index))
+}
+
+// ColumnTypeDatabaseTypeName returns the declared database type name of the column
+// or empty string if it was not declared.
+func (rc *RowsCursor) ColumnTypeDatabaseTypeName(index int) string {
+	if rc.posSet < len(rc.colType) && index < len(rc.colType[rc.posSet]) {
+		return rc.colType[rc.posSet][index]
+	}
+	return ""
 }
 
 // Next is called to populate the next row of data into
-// the provided slice.
+// the provided slice. Values are passed as they are in the response without any coercion,
+// so sql.Scanner destinations receive the original string or []byte.
 func (rc *RowsCursor) Next(accumulator []driver.Value) error {
 	if rc.closed {
 		return errors.New("fake_db_driver: cursor is closed")
@@ -61,10 +91,19 @@ func (rc *RowsCursor) Next(accumulator []driver.Value) error {
 	if rc.posRow == rc.errPos {
 		return rc.err
 	}
-	if rc.posRow >= len(rc.rows[rc.posSet]) {
+	if err, ok := rc.rowErrors[rc.posRow]; ok {
+		return err
+	}
+	if rc.stream != nil {
+		return rc.nextStreamed(accumulator)
+	}
+	if rc.posSet >= len(rc.rows) || rc.posRow >= len(rc.rows[rc.posSet]) {
 		return io.EOF // per interface spec
 	}
 	for i, v := range rc.rows[rc.posSet][rc.posRow].cols {
+		if _, ok := v.(func() driver.Value); ok {
+			v = rc.emit(i, v)
+		}
 		accumulator[i] = v
 		if bs, ok := v.([]byte); ok {
 			if rc.bytesClone == nil {
@@ -82,8 +121,60 @@ func (rc *RowsCursor) Next(accumulator []driver.Value) error {
 	return nil
 }
 
+// nextStreamed waits for the next row from stream
+func (rc *RowsCursor) nextStreamed(accumulator []driver.Value) error {
+	select {
+	case <-rc.ctx.Done():
+		return rc.ctx.Err()
+	case record, ok := <-rc.stream:
+		if !ok {
+			return io.EOF
+		}
+		for i, col := range rc.cols {
+			accumulator[i] = rc.emit(i, record[col])
+		}
+		return nil
+	}
+}
+
+// emit computes lazy func() driver.Value of the column and encodes the value as the row is emitted
+func (rc *RowsCursor) emit(index int, v interface{}) driver.Value {
+	if lazy, ok := v.(func() driver.Value); ok {
+		v = lazy()
+	}
+	return encodeValue(rc.encoders, rc.validate, rc.cols[index], rc.posRow, v)
+}
+
+// encodeValue applies encoder of the column to the value, with validate values database/sql can not scan are logged
+func encodeValue(encoders map[string]func(interface{}) driver.Value, validate bool, col string, rowIndex int, v interface{}) driver.Value {
+	if encoder, ok := encoders[col]; ok {
+		v = encoder(v)
+	}
+	if validate && !isScannable(v) {
+		log.Printf("mock_catcher: value of column %q in row %d has type %T which database/sql can not scan, "+
+			"use int64, float64, bool, []byte, string or time.Time", col, rowIndex, v)
+	}
+	return v
+}
+
+// maps returns rows of the first result set keyed by column names
+func (rc *RowsCursor) maps() []map[string]interface{} {
+	if len(rc.rows) == 0 {
+		return nil
+	}
+	records := make([]map[string]interface{}, len(rc.rows[0]))
+	for i, r := range rc.rows[0] {
+		records[i] = make(map[string]interface{}, len(rc.cols))
+		for j, col := range rc.cols {
+			records[i][col] = r.cols[j]
+		}
+	}
+	return records
+}
+
 // HasNextResultSet is called at the end of the current result set and
 // reports whether there is another result set after the current one.
+// It is always false for single result set responses.
 func (rc *RowsCursor) HasNextResultSet() bool {
 	return rc.posSet < len(rc.rows)-1
 }
@@ -99,28 +190,56 @@ func (rc *RowsCursor) NextResultSet() error {
 	return io.EOF // Per interface spec.
 }
 
+var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// inferColType returns database type name for Go type of the value, empty for NULL and unknown types
+func inferColType(value interface{}) string {
+	switch value.(type) {
+	case int64, int, int32, int16, int8, uint32, uint16, uint8:
+		return "BIGINT"
+	case float64, float32:
+		return "DOUBLE"
+	case string:
+		return "VARCHAR"
+	case bool:
+		return "BOOLEAN"
+	case time.Time:
+		return "TIMESTAMP"
+	case []byte:
+		return "BLOB"
+	}
+	return ""
+}
+
+// colTypeToReflectType maps database type name like VARCHAR(255) or BIGINT to Go type to scan into.
+// Unknown types are scanned into interface{}
 func colTypeToReflectType(typ string) reflect.Type {
-	switch typ {
-	case "bool":
+	if i := strings.IndexByte(typ, '('); i >= 0 {
+		typ = typ[:i]
+	}
+	switch strings.ToLower(strings.TrimSpace(typ)) {
+	case "bool", "boolean":
 		return reflect.TypeOf(false)
 	case "nullbool":
 		return reflect.TypeOf(sql.NullBool{})
 	case "int32":
 		return reflect.TypeOf(int32(0))
-	case "string":
+	case "string", "varchar", "char", "text", "nvarchar", "nchar", "uuid":
 		return reflect.TypeOf("")
 	case "nullstring":
 		return reflect.TypeOf(sql.NullString{})
-	case "int64":
+	case "int64", "int", "integer", "bigint", "smallint", "tinyint", "serial", "bigserial":
 		return reflect.TypeOf(int64(0))
 	case "nullint64":
 		return reflect.TypeOf(sql.NullInt64{})
-	case "float64":
+	case "float64", "float", "double", "real", "numeric", "decimal":
 		return reflect.TypeOf(float64(0))
 	case "nullfloat64":
 		return reflect.TypeOf(sql.NullFloat64{})
-	case "datetime":
+	case "datetime", "date", "timestamp", "timestamptz", "time":
 		return reflect.TypeOf(time.Time{})
+	case "blob", "bytea", "binary", "varbinary", "bytes":
+		return reflect.TypeOf([]byte(nil))
 	}
-	panic("invalid fakedb column type of " + typ)
+	return interfaceType
 }
@@ -0,0 +1,25 @@
+//go:build go1.27
+
+package gomocket
+
+import (
+	"database/sql"
+	"database/sql/driver"
+)
+
+// NextRow advances to the next row keeping its values for ScanColumn
+func (rc *RowsCursor) NextRow() error {
+	if len(rc.current) != len(rc.cols) {
+		rc.current = make([]driver.Value, len(rc.cols))
+	}
+	return rc.Next(rc.current)
+}
+
+// ScanColumn copies value of the column in the current row into dest,
+// columns set by WithConvertError fail with their error instead
+func (rc *RowsCursor) ScanColumn(scanCtx driver.ScanContext, index int, dest any) error {
+	if err, ok := rc.convertErrors[rc.cols[index]]; ok {
+		return err
+	}
+	return sql.ConvertAssign(scanCtx, dest, rc.current[index])
+}
@@ -0,0 +1,36 @@
+//go:build go1.27
+
+package gomocket
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestConvertError(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	convertErr := errors.New("bad age")
+	Catcher.Reset().NewMock().WithQuery("SELECT name, age").WithColumns("name", "age").WithConvertError("age", convertErr).
+		WithReply([]map[string]interface{}{{"name": "FirstLast", "age": 30}})
+
+	rows, err := db.Query("SELECT name, age FROM users")
+	if err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		t.Fatalf("Row is expected [%v]", rows.Err())
+	}
+	var name string
+	var age int
+	err = rows.Scan(&name, &age)
+	if !errors.Is(err, convertErr) || !strings.Contains(err.Error(), `"age"`) {
+		t.Errorf("Scan error mismatches. Expected: [%v] , Got: [%v]", convertErr, err)
+	}
+	if name != "FirstLast" {
+		t.Errorf("Name mismatches. Expected: [%v] , Got: [%v]", "FirstLast", name)
+	}
+}
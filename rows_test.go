@@ -0,0 +1,517 @@
+package gomocket
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decimal is a custom sql.Scanner for monetary values
+type decimal struct {
+	big.Rat
+}
+
+func (d *decimal) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("unsupported decimal source %T", src)
+	}
+	if _, ok := d.SetString(s); !ok {
+		return fmt.Errorf("invalid decimal %q", s)
+	}
+	return nil
+}
+
+func TestCustomScanner(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+
+	for _, value := range []interface{}{"12.34", []byte("12.34")} {
+		Catcher.Reset().NewMock().WithQuery("SELECT price FROM goods").WithReply([]map[string]interface{}{{"price": value}})
+		var price decimal
+		if err := db.QueryRow("SELECT price FROM goods").Scan(&price); err != nil {
+			t.Fatalf("Scan of %T failed [%v]", value, err)
+		}
+		if price.FloatString(2) != "12.34" {
+			t.Errorf("Price mismatches. Expected: [%v] , Got: [%v]", "12.34", price.FloatString(2))
+		}
+	}
+}
+
+func TestRowError(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	rowErr := errors.New("connection reset")
+	Catcher.Reset().NewMock().WithQuery("SELECT id FROM users").WithReplyInts("id", []int64{1, 2, 3, 4}).WithRowError(2, rowErr)
+
+	rows, err := db.Query("SELECT id FROM users")
+	if err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("Scan failed [%v]", err)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) != 2 {
+		t.Errorf("Rows count mismatches. Expected: [%v] , Got: [%v]", 2, len(ids))
+	}
+	if rows.Err() != rowErr {
+		t.Errorf("Rows error mismatches. Expected: [%v] , Got: [%v]", rowErr, rows.Err())
+	}
+}
+
+func TestColumnScanTypes(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT * FROM users").
+		WithReply([]map[string]interface{}{{"name": "FirstLast", "age": int64(30), "avatar": []byte{1}, "score": 1.5, "born": time.Now(), "extra": nil, "nick": "nick"}}).
+		WithColumnTypes(
+			ColumnMeta{Name: "name", Type: "VARCHAR(255)"},
+			ColumnMeta{Name: "age", Type: "BIGINT"},
+			ColumnMeta{Name: "avatar", Type: "BLOB"},
+			ColumnMeta{Name: "score", Type: "DOUBLE"},
+			ColumnMeta{Name: "born", Type: "TIMESTAMP"},
+			ColumnMeta{Name: "nick", Type: "VARCHAR", ScanType: reflect.TypeOf(sql.NullString{})},
+		)
+
+	rows, err := db.Query("SELECT * FROM users")
+	if err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("Column types failed [%v]", err)
+	}
+
+	expected := map[string]reflect.Type{
+		"name":   reflect.TypeOf(""),
+		"age":    reflect.TypeOf(int64(0)),
+		"avatar": reflect.TypeOf([]byte(nil)),
+		"score":  reflect.TypeOf(float64(0)),
+		"born":   reflect.TypeOf(time.Time{}),
+		"extra":  reflect.TypeOf((*interface{})(nil)).Elem(),
+		"nick":   reflect.TypeOf(sql.NullString{}),
+	}
+	if len(types) != len(expected) {
+		t.Fatalf("Columns count mismatches. Expected: [%v] , Got: [%v]", len(expected), len(types))
+	}
+	for _, ct := range types {
+		if ct.ScanType() != expected[ct.Name()] {
+			t.Errorf("Scan type of %v mismatches. Expected: [%v] , Got: [%v]", ct.Name(), expected[ct.Name()], ct.ScanType())
+		}
+	}
+}
+
+// point is a custom sql.Scanner expecting "(x,y)" bytes like Postgres point
+type point struct {
+	X, Y int
+}
+
+func (p *point) Scan(src interface{}) error {
+	bs, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("unsupported point source %T", src)
+	}
+	_, err := fmt.Sscanf(string(bs), "(%d,%d)", &p.X, &p.Y)
+	return err
+}
+
+func TestValueEncoder(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT location FROM places").
+		WithReply([]map[string]interface{}{{"location": point{X: 3, Y: 4}}}).
+		WithValueEncoder("location", func(v interface{}) driver.Value {
+			p := v.(point)
+			return []byte(fmt.Sprintf("(%d,%d)", p.X, p.Y))
+		})
+
+	var location point
+	if err := db.QueryRow("SELECT location FROM places").Scan(&location); err != nil {
+		t.Fatalf("Scan failed [%v]", err)
+	}
+	if location.X != 3 || location.Y != 4 {
+		t.Errorf("Location mismatches. Expected: [%v] , Got: [%v]", point{3, 4}, location)
+	}
+}
+
+func TestColumnsWithNoRows(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT COUNT(*) AS total, department FROM users").WithColumns("total", "department").WithNoRows()
+
+	rows, err := db.Query("SELECT COUNT(*) AS total, department FROM users GROUP BY department")
+	if err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		t.Fatalf("Columns failed [%v]", err)
+	}
+	if !reflect.DeepEqual(columns, []string{"total", "department"}) {
+		t.Errorf("Columns mismatch. Expected: [%v] , Got: [%v]", []string{"total", "department"}, columns)
+	}
+	if rows.Next() {
+		t.Errorf("Next should return false for empty result")
+	}
+}
+
+func TestValidateScannable(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	Catcher.Reset().NewMock().WithQuery("SELECT * FROM users").ValidateScannable().
+		WithReply([]map[string]interface{}{{"name": "FirstLast", "age": 30, "address": struct{ City string }{"Kyiv"}}})
+	rows, err := db.Query("SELECT * FROM users")
+	if err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	rows.Close()
+
+	warnings := buf.String()
+	if !strings.Contains(warnings, `column "address" in row 0`) {
+		t.Errorf("Warning for struct value not logged. Got: [%v]", warnings)
+	}
+	if strings.Contains(warnings, `"name"`) || strings.Contains(warnings, `"age"`) {
+		t.Errorf("Scannable values should not be reported. Got: [%v]", warnings)
+	}
+}
+
+func TestRowErrors(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	rowErr := errors.New("corrupted record")
+	Catcher.Reset().NewMock().WithQuery("SELECT id FROM users").WithReplyInts("id", []int64{1, 2, 3, 4, 5}).
+		WithRowErrors(map[int]error{3: rowErr, 4: errors.New("never reached")})
+
+	rows, err := db.Query("SELECT id FROM users")
+	if err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("Scan failed [%v]", err)
+		}
+		ids = append(ids, id)
+	}
+	if !reflect.DeepEqual(ids, []int64{1, 2, 3}) {
+		t.Errorf("Rows before error mismatch. Expected: [%v] , Got: [%v]", []int64{1, 2, 3}, ids)
+	}
+	if rows.Err() != rowErr {
+		t.Errorf("Rows error mismatches. Expected: [%v] , Got: [%v]", rowErr, rows.Err())
+	}
+}
+
+func TestSingleResultSet(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT id FROM users").WithReplyInts("id", []int64{1, 2})
+
+	rows, err := db.Query("SELECT id FROM users")
+	if err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	defer rows.Close()
+	count := 0
+	for rows.Next() {
+		count++
+	}
+	if count != 2 {
+		t.Errorf("Rows count mismatches. Expected: [%v] , Got: [%v]", 2, count)
+	}
+	if rows.NextResultSet() {
+		t.Errorf("Single result set should not report next result set")
+	}
+	if rows.Err() != nil {
+		t.Errorf("Probing next result set should not fail. Got: [%v]", rows.Err())
+	}
+
+	cursor := &RowsCursor{posRow: -1, rows: [][]*row{{}}, errPos: -1}
+	if cursor.HasNextResultSet() {
+		t.Errorf("HasNextResultSet should be false for single result set")
+	}
+	if err := cursor.NextResultSet(); err != io.EOF {
+		t.Errorf("NextResultSet mismatches. Expected: [%v] , Got: [%v]", io.EOF, err)
+	}
+	if err := (&RowsCursor{posRow: -1, errPos: -1}).Next(nil); err != io.EOF {
+		t.Errorf("Next of cursor without result sets mismatches. Expected: [%v] , Got: [%v]", io.EOF, err)
+	}
+}
+
+func TestCursorPages(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("FETCH").WithCursorPages(
+		[]map[string]interface{}{{"id": int64(1)}, {"id": int64(2)}},
+		[]map[string]interface{}{{"id": int64(3)}},
+	)
+
+	for run := 0; run < 2; run++ {
+		rows, err := db.Query("FETCH 2 FROM users_cursor")
+		if err != nil {
+			t.Fatalf("Query failed [%v]", err)
+		}
+		var pages [][]int64
+		for {
+			var page []int64
+			for rows.Next() {
+				var id int64
+				rows.Scan(&id)
+				page = append(page, id)
+			}
+			pages = append(pages, page)
+			if !rows.NextResultSet() {
+				break
+			}
+		}
+		rows.Close()
+		expected := [][]int64{{1, 2}, {3}}
+		if !reflect.DeepEqual(pages, expected) {
+			t.Errorf("Run %d: pages mismatch. Expected: [%v] , Got: [%v]", run, expected, pages)
+		}
+	}
+}
+
+func TestReplyChannel(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	ch := make(chan map[string]interface{})
+	Catcher.Reset().NewMock().WithQuery("SELECT id FROM events").WithColumns("id").WithReplyChannel(ch)
+
+	go func() {
+		for i := int64(1); i <= 3; i++ {
+			ch <- map[string]interface{}{"id": i}
+		}
+		close(ch)
+	}()
+	rows, err := db.Query("SELECT id FROM events")
+	if err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if !reflect.DeepEqual(ids, []int64{1, 2, 3}) || rows.Err() != nil {
+		t.Errorf("Streamed rows mismatch. Expected: [%v] , Got: [%v] [%v]", []int64{1, 2, 3}, ids, rows.Err())
+	}
+
+	Catcher.Reset().NewMock().WithQuery("SELECT id FROM events").WithColumns("id").WithReplyChannel(make(chan map[string]interface{}))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	rows, err = db.QueryContext(ctx, "SELECT id FROM events")
+	if err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	defer rows.Close()
+	if rows.Next() {
+		t.Errorf("No rows should be emitted before context is done")
+	}
+	if rows.Err() != context.DeadlineExceeded {
+		t.Errorf("Rows error mismatches. Expected: [%v] , Got: [%v]", context.DeadlineExceeded, rows.Err())
+	}
+}
+
+func TestRaggedRows(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT * FROM events").WithReply([]map[string]interface{}{
+		{"id": int64(1)},
+		{"id": int64(2), "name": "second"},
+		{"kind": "third"},
+	})
+
+	rows := queryMaps(t, db, "SELECT * FROM events")
+	expected := []map[string]interface{}{
+		{"id": int64(1), "name": nil, "kind": nil},
+		{"id": int64(2), "name": "second", "kind": nil},
+		{"id": nil, "name": nil, "kind": "third"},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Rows mismatch. Expected: [%v] , Got: [%v]", expected, rows)
+	}
+}
+
+func TestColumnsFromFirstRow(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT * FROM users").ColumnsFromFirstRow().WithReply([]map[string]interface{}{
+		{"name": "First", "id": int64(1), "age": int64(30)},
+		{"name": "Second", "id": int64(2), "email": "second@example.com"},
+	})
+
+	rows, err := db.Query("SELECT * FROM users")
+	if err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	defer rows.Close()
+	columns, _ := rows.Columns()
+	if expected := []string{"age", "id", "name"}; !reflect.DeepEqual(columns, expected) {
+		t.Errorf("Columns mismatch. Expected: [%v] , Got: [%v]", expected, columns)
+	}
+}
+
+func TestReplyData(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT id, name FROM users").WithReplyData([]string{"id", "name"}, [][]driver.Value{
+		{int64(1), "First"},
+		{int64(2), nil},
+	})
+
+	rows, err := db.Query("SELECT id, name FROM users")
+	if err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var id int64
+		var name sql.NullString
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatalf("Scan failed [%v]", err)
+		}
+		got = append(got, fmt.Sprintf("%d:%v:%v", id, name.Valid, name.String))
+	}
+	if expected := []string{"1:true:First", "2:false:"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Rows mismatch. Expected: [%v] , Got: [%v]", expected, got)
+	}
+
+	Catcher.Reset().NewMock().WithQuery("SELECT id").WithReplyData([]string{"id"}, [][]driver.Value{{int64(1), "extra"}})
+	if _, err := db.Query("SELECT id FROM users"); err == nil {
+		t.Errorf("Row with more values than columns should fail the query")
+	}
+
+	Catcher.Reset().NewMock().WithQuery("SELECT id, name FROM users").WithReplyData([]string{"id", "name"}, [][]driver.Value{
+		{int64(3), "Third"}, {int64(1), "First"}, {int64(2), "Second"}, {int64(4), "Fourth"},
+	}).WithOrderBy("id", true).Paginated().WithMaxRows(2).WithValueEncoder("name", func(v interface{}) driver.Value {
+		return strings.ToUpper(v.(string))
+	})
+	result := queryMaps(t, db, "SELECT id, name FROM users LIMIT 3 OFFSET 1")
+	if len(result) != 2 || result[0]["name"] != "THIRD" || result[1]["name"] != "SECOND" {
+		t.Errorf("Reply data should be sorted, paginated, capped and encoded. Got: [%v]", result)
+	}
+}
+
+func TestInferColumnTypes(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("SELECT * FROM users").InferColumnTypes().
+		WithColumns("id", "score", "name", "active", "created", "avatar", "note", "age").
+		WithColumnTypes(ColumnMeta{Name: "age", Type: "SMALLINT"}).
+		WithReply([]map[string]interface{}{{
+			"id": int64(1), "score": 0.5, "name": "FirstLast", "active": true,
+			"created": time.Now(), "avatar": []byte{1}, "note": nil, "age": int64(30),
+		}})
+
+	rows, err := db.Query("SELECT * FROM users")
+	if err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	defer rows.Close()
+	columnTypes, _ := rows.ColumnTypes()
+	var got []string
+	for _, ct := range columnTypes {
+		got = append(got, ct.DatabaseTypeName())
+	}
+	if expected := []string{"BIGINT", "DOUBLE", "VARCHAR", "BOOLEAN", "TIMESTAMP", "BLOB", "", "SMALLINT"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Column types mismatch. Expected: [%v] , Got: [%v]", expected, got)
+	}
+
+	Catcher.Reset().NewMock().WithQuery("SELECT a, b").WithReplyData([]string{"a", "b"}, [][]driver.Value{{int64(1)}}).InferColumnTypes()
+	if _, err := db.Query("SELECT a, b FROM t"); err == nil {
+		t.Errorf("Reply data narrower than columns should fail the query")
+	}
+}
+
+func TestLazyValues(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	calls := 0
+	avatar := func() driver.Value {
+		calls++
+		return bytes.Repeat([]byte{0xFF}, 1024)
+	}
+	Catcher.Reset().NewMock().WithQuery("SELECT name, avatar FROM users").WithColumns("name", "avatar").
+		WithReply([]map[string]interface{}{{"name": "First", "avatar": avatar}, {"name": "Second", "avatar": avatar}})
+
+	rows, err := db.Query("SELECT name, avatar FROM users")
+	if err != nil {
+		t.Fatalf("Query failed [%v]", err)
+	}
+	if calls != 0 {
+		t.Errorf("Lazy value should not be computed before its row is emitted. Calls: [%v]", calls)
+	}
+	rows.Next()
+	var name string
+	var data []byte
+	if err := rows.Scan(&name, &data); err != nil {
+		t.Fatalf("Scan failed [%v]", err)
+	}
+	rows.Close()
+	if calls != 1 || len(data) != 1024 {
+		t.Errorf("Lazy value should be computed once for emitted row. Calls: [%v] , Length: [%v]", calls, len(data))
+	}
+
+	Catcher.Reset().NewMock().WithQuery("SELECT name FROM users").
+		WithReply([]map[string]interface{}{{"name": func() driver.Value { return "first" }}}).
+		WithValueEncoder("name", func(v interface{}) driver.Value { return fmt.Sprintf("enc:%v", v) })
+	if err := db.QueryRow("SELECT name FROM users").Scan(&name); err != nil || name != "enc:first" {
+		t.Errorf("Encoder should get computed lazy value. Expected: [%v] , Got: [%v] [%v]", "enc:first", name, err)
+	}
+}
+
+func TestEmittedColumns(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	fr := Catcher.Reset().NewMock().WithQuery("SELECT").WithReply([]map[string]interface{}{{"id": int64(1), "name": "FirstLast", "age": int64(30)}})
+
+	if len(fr.EmittedColumns()) != 0 {
+		t.Errorf("Mock which did not serve should have no emitted columns. Got: [%v]", fr.EmittedColumns())
+	}
+	rows, _ := db.Query("SELECT * FROM users")
+	columns, _ := rows.Columns()
+	rows.Close()
+	if !reflect.DeepEqual(fr.EmittedColumns(), columns) {
+		t.Errorf("Emitted columns mismatch. Expected: [%v] , Got: [%v]", columns, fr.EmittedColumns())
+	}
+
+	fr.WithColumns("name", "id", "age")
+	rows, _ = db.Query("SELECT * FROM users")
+	rows.Close()
+	if !reflect.DeepEqual(fr.EmittedColumns(), []string{"name", "id", "age"}) {
+		t.Errorf("Declared columns order mismatch. Got: [%v]", fr.EmittedColumns())
+	}
+}
@@ -5,20 +5,24 @@ import (
 	"database/sql/driver"
 	"errors"
 	"fmt"
-	"math/rand"
+	"reflect"
+	"sort"
 	"strings"
 )
 
 // FakeStmt  is implementation of Stmt sql interfcae
 type FakeStmt struct {
 	connection   *FakeConn
-	q            string    // just for debugging SQL query generated by sql package
-	command      string    // String name of the command SELECT etc, taken as first word in the query
-	next         *FakeStmt // used for returning multiple results.
-	closed       bool      // If connection closed already
-	colName      []string  // Names of columns in response
-	colType      []string  // Not used for now
-	placeholders int       // Amount of passed args
+	q            string          // just for debugging SQL query generated by sql package
+	command      string          // String name of the command SELECT etc, taken as first word in the query
+	next         *FakeStmt       // used for returning multiple results.
+	responses    []*FakeResponse // Mocks matched by query at Prepare, nil for direct queries
+	closed       bool            // If connection closed already
+	colName      []string        // Names of columns in response
+	colType      []string        // Not used for now
+	placeholders int             // Amount of passed args
+	uses         int             // How many times the statement was executed
+	maxUses      int             // Executions allowed before ErrStmtExpired, 0 means unlimited
 }
 
 // ColumnConverter returns a ValueConverter for the provided
@@ -42,7 +46,7 @@ func (s *FakeStmt) Close() error {
 	if s.next != nil {
 		s.next.Close()
 	}
-	return nil
+	return s.connection.catcher.stmtCloseError(s.q)
 }
 
 var errClosed = errors.New("fake_db_driver: statement has been closed")
@@ -62,35 +66,91 @@ func (s *FakeStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (d
 		return nil, errClosed
 	}
 
-	fResp := Catcher.FindResponse(s.q, args)
+	if err := s.connection.catcher.downError(); err != nil {
+		return nil, err
+	}
+
+	if err := s.connection.catcher.useQuota(); err != nil {
+		return nil, err
+	}
+
+	if err := s.use(); err != nil {
+		return nil, err
+	}
+
+	if tx := s.connection.currTx; tx != nil && tx.opts.ReadOnly && s.connection.catcher.ReadOnlyExecError != nil {
+		return nil, s.connection.catcher.ReadOnlyExecError
+	}
+
+	start := s.connection.catcher.clock().Now()
+	call := &queryCall{prepared: s.responses, tx: s.connection.currTx, requestID: requestIDFrom(ctx), placeholders: s.placeholders}
+	fResp := s.connection.catcher.handle(s.q, args, call)
+	err := fResp.wait(ctx, s.connection.catcher.clock())
+	s.connection.catcher.finish(call, start)
+	if err != nil {
+		return nil, err
+	}
+
+	if fResp.passthrough != nil {
+		res, err := fResp.passthrough.ExecContext(ctx, s.q, passthroughArgs(args)...)
+		if err == nil {
+			s.connection.catcher.recordReply(call, nil, nil, res)
+		}
+		return res, err
+	}
 
 	// To emulate any exception during query which returns rows
+	if fResp.ReplyError != nil {
+		return nil, fResp.ReplyError
+	}
 	if fResp.Exceptions != nil && fResp.Exceptions.HookExecBadConnection != nil && fResp.Exceptions.HookExecBadConnection() {
 		return nil, driver.ErrBadConn
 	}
 
-	if fResp.Error != nil {
-		return nil, fResp.Error
+	rowsAffected, lastInsertID, respErr := fResp.RowsAffected, fResp.LastInsertID, fResp.Error
+	if fResp.Responder != nil {
+		_, rowsAffected, lastInsertID, respErr = fResp.Responder(s.q, args)
+	}
+	if respErr != nil {
+		return nil, respErr
 	}
 
 	if fResp.Callback != nil {
 		fResp.Callback(s.q, args)
 	}
+	if fResp.MetaCallback != nil {
+		fResp.MetaCallback(s.q, args, fResp.meta())
+	}
 
-	switch s.command {
+	fResp.recordExec(s.q)
+	matched := call.record != nil && call.record.Matched // Defaults apply to matched mocks only
+	if rowsAffected == 0 && matched {
+		rowsAffected = s.connection.catcher.DefaultRowsAffected
+	}
+	command := fResp.execCommand(s.q, s.connection.catcher.Dialect, s.command)
+	var res driver.Result
+	switch command {
 	case "INSERT":
-		id := fResp.LastInsertID
+		id := lastInsertID
+		if id == 0 && matched {
+			id = s.connection.catcher.DefaultLastInsertId
+		}
 		if id == 0 {
-			id = rand.Int63()
+			id = s.connection.catcher.randInt63()
+		}
+		if rowsAffected == 0 {
+			rowsAffected = 1
 		}
-		res := NewFakeResult(id, 1)
-		return res, nil
+		res = NewFakeResult(id, rowsAffected)
 	case "UPDATE":
-		return driver.RowsAffected(fResp.RowsAffected), nil
+		res = driver.RowsAffected(rowsAffected)
 	case "DELETE":
-		return driver.RowsAffected(fResp.RowsAffected), nil
+		res = driver.RowsAffected(rowsAffected)
+	default:
+		return nil, fmt.Errorf("unimplemented statement Exec command type of %q", command)
 	}
-	return nil, fmt.Errorf("unimplemented statement Exec command type of %q", s.command)
+	s.connection.catcher.recordReply(call, nil, nil, res)
+	return res, nil
 }
 
 // Query executes a query that may return rows, such as a
@@ -109,74 +169,308 @@ func (s *FakeStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (
 		return nil, errClosed
 	}
 
+	if err := s.connection.catcher.downError(); err != nil {
+		return nil, err
+	}
+
+	if err := s.connection.catcher.useQuota(); err != nil {
+		return nil, err
+	}
+
+	if err := s.use(); err != nil {
+		return nil, err
+	}
+
+	query := s.q
 	if len(args) > 0 {
 		// Replace all "?" to "%v" and replace them with the values after
 		for i := 0; i < len(args); i++ {
-			s.q = strings.Replace(s.q, "?", "%v", 1)
-			s.q = fmt.Sprintf(s.q, args[i].Value)
+			query = strings.Replace(query, "?", "%v", 1)
+			query = fmt.Sprintf(query, args[i].Value)
 		}
 	}
 
-	fResp := Catcher.FindResponse(s.q, args)
+	start := s.connection.catcher.clock().Now()
+	call := &queryCall{prepared: s.responses, tx: s.connection.currTx, requestID: requestIDFrom(ctx), placeholders: s.placeholders}
+	fResp := s.connection.catcher.handle(query, args, call)
+	err := fResp.wait(ctx, s.connection.catcher.clock())
+	s.connection.catcher.finish(call, start)
+	if err != nil {
+		return nil, err
+	}
 
+	if fResp.passthrough != nil {
+		cursor, err := passthroughQuery(ctx, fResp.passthrough, s.q, args)
+		if err != nil {
+			return nil, err
+		}
+		s.connection.catcher.recordReply(call, cursor.cols, cursor.maps(), nil)
+		return cursor, nil
+	}
+
+	if fResp.ReplyError != nil {
+		return nil, fResp.ReplyError
+	}
 	if fResp.Exceptions != nil && fResp.Exceptions.HookQueryBadConnection != nil && fResp.Exceptions.HookQueryBadConnection() {
 		return nil, driver.ErrBadConn
 	}
 
-	if fResp.Error != nil {
-		return nil, fResp.Error
+	response, respErr := fResp.Response, fResp.Error
+	if len(fResp.RoundRobin) > 0 {
+		response = fResp.roundRobinReply()
+	}
+	if fResp.Responder != nil {
+		response, _, _, respErr = fResp.Responder(query, args)
+	}
+	if respErr != nil {
+		return nil, respErr
 	}
 
 	resultRows := make([][]*row, 0, 1)
 	columnNames := make([]string, 0, 1)
 	columnTypes := make([][]string, 0, 1)
-	rows := []*row{}
+	scanTypesPerSet := make([][]reflect.Type, 0, 1)
 
-	// Check if we have such query in the map
-	colIndexes := make(map[string]int)
+	if fResp.Table != nil && fResp.Responder == nil {
+		response = fResp.Table
+	}
+	pages := [][]map[string]interface{}{response}
+	if len(fResp.Pages) > 0 {
+		pages = fResp.Pages
+	}
+
+	// Collecting column names from declared columns, sorted keys of the first record
+	// or as union of keys of all records, records missing a column emit NULL for it
+	if len(fResp.Columns) > 0 {
+		columnNames = append(columnNames, fResp.Columns...)
+	} else if fResp.FirstRowCols {
+		if len(pages[0]) > 0 {
+			for colName := range pages[0][0] {
+				columnNames = append(columnNames, colName)
+			}
+			sort.Strings(columnNames)
+		}
+	} else {
+		seen := make(map[string]bool)
+		for _, page := range pages {
+			for _, record := range page {
+				for colName := range record {
+					if !seen[colName] {
+						seen[colName] = true
+						columnNames = append(columnNames, colName)
+					}
+				}
+			}
+		}
+	}
 
-	// Collecting column names from first record
-	if len(fResp.Response) > 0 {
-		for colName := range fResp.Response[0] {
-			colIndexes[colName] = len(columnNames)
-			columnNames = append(columnNames, colName)
+	// Every reply form is converted to rows of values in order of columns, then filtered, sorted and cut
+	data, err := fResp.replyData(pages, columnNames)
+	if err != nil {
+		return nil, err
+	}
+	if len(fResp.Pages) == 0 {
+		data[0] = fResp.transformRows(data[0], columnNames, query, args)
+	}
+
+	// Declared column types in order of columns, or inferred from values of the first row
+	types := make([]string, len(columnNames))
+	scanTypes := make([]reflect.Type, len(columnNames))
+	for i, col := range columnNames {
+		if meta, ok := fResp.columnMeta(col); ok {
+			types[i] = meta.Type
+			scanTypes[i] = meta.ScanType
+		} else if fResp.InferTypes && len(data[0]) > 0 {
+			types[i] = inferColType(data[0][0][i])
 		}
 	}
 
-	// Extracting values from result according columns
-	for _, record := range fResp.Response {
-		oneRow := &row{cols: make([]interface{}, len(columnNames))}
-		for _, col := range columnNames {
-			oneRow.cols[colIndexes[col]] = record[col]
+	// Every cursor page is a result set
+	for _, page := range data {
+		rows := make([]*row, len(page))
+		for i, values := range page {
+			oneRow := &row{cols: make([]interface{}, len(values))}
+			for j, value := range values {
+				if _, ok := value.(func() driver.Value); !ok { // lazy values are encoded when emitted
+					value = encodeValue(fResp.Encoders, fResp.Validate, columnNames[j], i, value)
+				}
+				oneRow.cols[j] = value
+			}
+			rows[i] = oneRow
 		}
-		rows = append(rows, oneRow)
+		resultRows = append(resultRows, rows)
+		columnTypes = append(columnTypes, types)
+		scanTypesPerSet = append(scanTypesPerSet, scanTypes)
 	}
-	resultRows = append(resultRows, rows)
 
 	cursor := &RowsCursor{
-		posRow:  -1,
-		rows:    resultRows,
-		cols:    columnNames,
-		colType: columnTypes, // TODO: implement support of that
-		errPos:  -1,
-		closed:  false,
+		posRow:   -1,
+		rows:     resultRows,
+		cols:     columnNames,
+		colType:  columnTypes,
+		scanType: scanTypesPerSet,
+		errPos:   -1,
+		closed:   false,
+		encoders: fResp.Encoders,
+		validate: fResp.Validate,
+	}
+	if fResp.RowError != nil {
+		cursor.errPos = fResp.RowErrorAt
+		cursor.err = fResp.RowError
+	}
+	if len(fResp.RowErrors) > 0 {
+		cursor.rowErrors = fResp.RowErrors
+	}
+	if len(fResp.ConvertErrors) > 0 {
+		cursor.convertErrors = fResp.ConvertErrors
+	}
+	if fResp.Stream != nil {
+		if len(columnNames) == 0 {
+			return nil, errors.New("fake_db_driver: columns of channel reply have to be declared by WithColumns")
+		}
+		cursor.stream = fResp.Stream
+		cursor.ctx = ctx
 	}
 
 	if fResp.Callback != nil {
-		fResp.Callback(s.q, args)
+		fResp.Callback(query, args)
+	}
+	if fResp.MetaCallback != nil {
+		fResp.MetaCallback(query, args, fResp.meta())
 	}
 
+	fResp.recordColumns(columnNames)
+	s.connection.catcher.recordReply(call, columnNames, cursor.maps(), nil)
 	return cursor, nil
 }
 
+// replyData returns rows of every cursor page as values in order of columns, it is the form all reply
+// helpers are converted to. WithReplyData rows are used as they are unless Responder produces the reply
+func (fr *FakeResponse) replyData(pages [][]map[string]interface{}, columns []string) ([][][]driver.Value, error) {
+	if fr.ReplyData != nil && fr.Responder == nil {
+		data := make([][]driver.Value, len(fr.ReplyData))
+		for i, values := range fr.ReplyData {
+			if len(values) != len(columns) {
+				return nil, fmt.Errorf("fake_db_driver: row %d of reply data has %d values for %d columns", i, len(values), len(columns))
+			}
+			data[i] = append([]driver.Value(nil), values...)
+		}
+		return [][][]driver.Value{data}, nil
+	}
+	data := make([][][]driver.Value, len(pages))
+	for p, page := range pages {
+		data[p] = make([][]driver.Value, len(page))
+		for i, record := range page {
+			values := make([]driver.Value, len(columns))
+			for j, col := range columns {
+				values[j] = record[col]
+			}
+			data[p][i] = values
+		}
+	}
+	return data, nil
+}
+
+// transformRows applies FilterBy, WithOrderBy, Paginated and WithMaxRows to rows of the reply.
+// Filter and order columns have to be among emitted columns, otherwise their values are NULLs
+func (fr *FakeResponse) transformRows(rows [][]driver.Value, columns []string, query string, args []driver.NamedValue) [][]driver.Value {
+	if fr.FilterColumn != "" && fr.Responder == nil {
+		rows = filterRows(rows, columnIndex(columns, fr.FilterColumn), args)
+	}
+	if fr.OrderBy != "" {
+		rows = sortRows(rows, columnIndex(columns, fr.OrderBy), fr.OrderDesc)
+	}
+	if fr.Paginate {
+		rows = paginate(rows, query)
+	}
+	if fr.MaxRows > 0 && fr.MaxRows < len(rows) {
+		rows = rows[:fr.MaxRows]
+	}
+	return rows
+}
+
+// columnIndex returns position of the column or -1 if it is not emitted
+func columnIndex(columns []string, column string) int {
+	for i, col := range columns {
+		if col == column {
+			return i
+		}
+	}
+	return -1
+}
+
+// columnValue returns value at the column index, nil for columns which are not emitted
+func columnValue(values []driver.Value, index int) driver.Value {
+	if index < 0 {
+		return nil
+	}
+	return values[index]
+}
+
+// paginate returns window of rows selected by trailing LIMIT and OFFSET of the query, all rows without them
+func paginate(rows [][]driver.Value, query string) [][]driver.Value {
+	limit, offset, ok := queryWindow(query)
+	if !ok {
+		return rows
+	}
+	if offset >= len(rows) {
+		return rows[:0]
+	}
+	rows = rows[offset:]
+	if limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+// filterRows returns rows whose value at the column index equals the first arg.
+// Without args all rows are returned
+func filterRows(rows [][]driver.Value, index int, args []driver.NamedValue) [][]driver.Value {
+	if len(args) == 0 {
+		return rows
+	}
+	filtered := make([][]driver.Value, 0)
+	for _, values := range rows {
+		value := columnValue(values, index)
+		if numbersEqual(value, args[0].Value) || reflect.DeepEqual(value, args[0].Value) {
+			filtered = append(filtered, values)
+		}
+	}
+	return filtered
+}
+
+// sortRows returns copy of rows sorted by the value at the column index
+func sortRows(rows [][]driver.Value, index int, desc bool) [][]driver.Value {
+	sorted := make([][]driver.Value, len(rows))
+	copy(sorted, rows)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if desc {
+			return lessValue(columnValue(sorted[j], index), columnValue(sorted[i], index))
+		}
+		return lessValue(columnValue(sorted[i], index), columnValue(sorted[j], index))
+	})
+	return sorted
+}
+
 // NumInput returns the number of placeholder parameters.
 func (s *FakeStmt) NumInput() int {
 	return s.placeholders
 }
 
+// use counts execution of the statement and returns ErrStmtExpired if it exceeds WithMaxStmtUses
+func (s *FakeStmt) use() error {
+	s.uses++
+	if s.maxUses > 0 && s.uses > s.maxUses {
+		return ErrStmtExpired
+	}
+	return nil
+}
+
 // FakeTx implements Tx interface
 type FakeTx struct {
-	c *FakeConn
+	c     *FakeConn
+	opts  driver.TxOptions // Options the transaction was begun with
+	ended bool             // Committed or rolled back, guarded by mu of the catcher
 }
 
 // HookBadCommit is a hook to simulate broken connections
@@ -185,6 +479,7 @@ var HookBadCommit func() bool
 // Commit commits the transaction
 func (tx *FakeTx) Commit() error {
 	tx.c.currTx = nil
+	tx.c.catcher.endTx(tx)
 	if HookBadCommit != nil && HookBadCommit() {
 		return driver.ErrBadConn
 	}
@@ -197,6 +492,7 @@ var HookBadRollback func() bool
 // Rollback rollbacks the transaction
 func (tx *FakeTx) Rollback() error {
 	tx.c.currTx = nil
+	tx.c.catcher.endTx(tx)
 	if HookBadRollback != nil && HookBadRollback() {
 		return driver.ErrBadConn
 	}
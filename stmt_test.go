@@ -0,0 +1,200 @@
+package gomocket
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestPreparedStatementMatch(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+
+	t.Run("Query matches statement text", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery("SELECT name FROM users WHERE id = ?").StrictMatch().WithArgs(int64(1)).
+			WithReply([]map[string]interface{}{{"name": "FirstLast"}})
+		stmt, err := db.Prepare("SELECT name FROM users WHERE id = ?")
+		if err != nil {
+			t.Fatalf("Prepare failed [%v]", err)
+		}
+		defer stmt.Close()
+
+		for i := 0; i < 2; i++ {
+			var name string
+			if err := stmt.QueryRow(1).Scan(&name); err != nil {
+				t.Fatalf("Query %d failed [%v]", i, err)
+			}
+			if name != "FirstLast" {
+				t.Errorf("Name mismatches. Expected: [%v] , Got: [%v]", "FirstLast", name)
+			}
+		}
+		if err := stmt.QueryRow(2).Scan(new(string)); err != sql.ErrNoRows {
+			t.Errorf("Other args should not match. Got: [%v]", err)
+		}
+	})
+
+	t.Run("Exec matches statement text", func(t *testing.T) {
+		Catcher.Reset().NewMock().WithQuery("UPDATE users SET age = ? WHERE id = ?").StrictMatch().WithArgs(int64(27), int64(1)).WithRowsNum(1)
+		stmt, err := db.Prepare("UPDATE users SET age = ? WHERE id = ?")
+		if err != nil {
+			t.Fatalf("Prepare failed [%v]", err)
+		}
+		defer stmt.Close()
+
+		res, err := stmt.Exec(27, 1)
+		if err != nil {
+			t.Fatalf("Exec failed [%v]", err)
+		}
+		if affected, _ := res.RowsAffected(); affected != 1 {
+			t.Errorf("Rows affected mismatches. Expected: [%v] , Got: [%v]", 1, affected)
+		}
+	})
+
+	t.Run("Reused statement picks mock by args", func(t *testing.T) {
+		Catcher.Reset()
+		for id, name := range map[int64]string{1: "First", 2: "Second"} {
+			Catcher.NewMock().WithQuery("SELECT name FROM users WHERE id = ?").StrictMatch().WithArgs(id).
+				WithReply([]map[string]interface{}{{"name": name}})
+		}
+		stmt, err := db.Prepare("SELECT name FROM users WHERE id = ?")
+		if err != nil {
+			t.Fatalf("Prepare failed [%v]", err)
+		}
+		defer stmt.Close()
+
+		for id, expected := range map[int64]string{1: "First", 2: "Second"} {
+			var name string
+			if err := stmt.QueryRow(id).Scan(&name); err != nil {
+				t.Fatalf("Query of %d failed [%v]", id, err)
+			}
+			if name != expected {
+				t.Errorf("Name mismatches. Expected: [%v] , Got: [%v]", expected, name)
+			}
+		}
+	})
+}
+
+func TestMaxStmtUses(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	Catcher.Reset().NewMock().WithQuery("UPDATE users SET age = ?").WithMaxStmtUses(2).WithRowsNum(1)
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Conn failed [%v]", err)
+	}
+	defer conn.Close()
+	stmt, err := conn.PrepareContext(context.Background(), "UPDATE users SET age = ?")
+	if err != nil {
+		t.Fatalf("Prepare failed [%v]", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := stmt.Exec(27); err != nil {
+			t.Fatalf("Exec %d failed [%v]", i, err)
+		}
+	}
+	if _, err := stmt.Exec(27); err != ErrStmtExpired {
+		t.Errorf("Error mismatches. Expected: [%v] , Got: [%v]", ErrStmtExpired, err)
+	}
+	stmt.Close()
+
+	stmt, err = conn.PrepareContext(context.Background(), "UPDATE users SET age = ?")
+	if err != nil {
+		t.Fatalf("Prepare failed [%v]", err)
+	}
+	defer stmt.Close()
+	if _, err := stmt.Exec(27); err != nil {
+		t.Errorf("Statement prepared again should run [%v]", err)
+	}
+}
+
+func TestLastExecQuery(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	fr := Catcher.Reset().NewMock().WithQuery("UPDATE users").WithRowsNum(1)
+
+	if fr.LastExecQuery() != "" {
+		t.Errorf("Mock without Exec should have no exec query. Got: [%v]", fr.LastExecQuery())
+	}
+	db.Exec("UPDATE users SET age = ? WHERE id = ?", 27, 1)
+	db.Exec("UPDATE users SET name = ? WHERE id = ?", "FirstLast", 2)
+	if fr.LastExecQuery() != "UPDATE users SET name = ? WHERE id = ?" {
+		t.Errorf("Exec query mismatches. Expected: [%v] , Got: [%v]", "UPDATE users SET name = ? WHERE id = ?", fr.LastExecQuery())
+	}
+	db.Query("SELECT * FROM users")
+	if fr.LastExecQuery() != "UPDATE users SET name = ? WHERE id = ?" {
+		t.Errorf("Queries should not change exec query. Got: [%v]", fr.LastExecQuery())
+	}
+}
+
+func TestResponder(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	notFound := errors.New("user not found")
+	Catcher.Reset().NewMock().WithQuery("SELECT name FROM users").WithResponder(
+		func(query string, args []driver.NamedValue) ([]map[string]interface{}, int64, int64, error) {
+			if args[0].Value == int64(0) {
+				return nil, 0, 0, notFound
+			}
+			return []map[string]interface{}{{"name": fmt.Sprintf("user%d", args[0].Value)}}, 0, 0, nil
+		})
+	Catcher.NewMock().WithQuery("INSERT INTO users").WithResponder(
+		func(query string, args []driver.NamedValue) ([]map[string]interface{}, int64, int64, error) {
+			return nil, int64(len(args)), args[0].Value.(int64) * 10, nil
+		})
+
+	var name string
+	if err := db.QueryRow("SELECT name FROM users WHERE id = ?", 7).Scan(&name); err != nil || name != "user7" {
+		t.Errorf("Responder rows mismatch. Got: [%v] [%v]", name, err)
+	}
+	if err := db.QueryRow("SELECT name FROM users WHERE id = ?", 0).Scan(&name); err != notFound {
+		t.Errorf("Responder error mismatches. Expected: [%v] , Got: [%v]", notFound, err)
+	}
+	res, err := db.Exec("INSERT INTO users (id, name) VALUES (?, ?)", 4, "user4")
+	if err != nil {
+		t.Fatalf("Exec failed [%v]", err)
+	}
+	id, _ := res.LastInsertId()
+	affected, _ := res.RowsAffected()
+	if id != 40 || affected != 2 {
+		t.Errorf("Responder result mismatches. Expected: [%v %v] , Got: [%v %v]", 40, 2, id, affected)
+	}
+}
+
+func TestPaginated(t *testing.T) {
+	Catcher.Register()
+	db, _ := sql.Open(DriverName, "connection_string")
+	table := make([]map[string]interface{}, 5)
+	for i := range table {
+		table[i] = map[string]interface{}{"id": int64(i + 1)}
+	}
+	Catcher.Reset().NewMock().WithQuery("SELECT id FROM users").WithTable(table).Paginated()
+
+	pageIDs := func(query string, args ...interface{}) []int64 {
+		var ids []int64
+		for _, record := range queryMaps(t, db, query, args...) {
+			ids = append(ids, record["id"].(int64))
+		}
+		return ids
+	}
+	for _, tc := range []struct {
+		offset   int
+		expected []int64
+	}{
+		{0, []int64{1, 2}},
+		{2, []int64{3, 4}},
+		{4, []int64{5}},
+		{6, nil},
+	} {
+		if ids := pageIDs("SELECT id FROM users LIMIT ? OFFSET ?", 2, tc.offset); !reflect.DeepEqual(ids, tc.expected) {
+			t.Errorf("Page at offset %d mismatches. Expected: [%v] , Got: [%v]", tc.offset, tc.expected, ids)
+		}
+	}
+	if ids := pageIDs("SELECT id FROM users"); len(ids) != 5 {
+		t.Errorf("Query without LIMIT should get all rows. Got: [%v]", ids)
+	}
+}
@@ -0,0 +1,149 @@
+package gomocket
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"time"
+)
+
+// Ranks of value kinds, values of different kinds are ordered by rank
+const (
+	rankNil = iota
+	rankBool
+	rankNumber
+	rankString
+	rankTime
+	rankOther
+)
+
+// valueRank returns rank of the value kind
+func valueRank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return rankNil
+	case bool:
+		return rankBool
+	case string, []byte:
+		return rankString
+	case time.Time:
+		return rankTime
+	}
+	if _, ok := toFloat64(v); ok {
+		return rankNumber
+	}
+	return rankOther
+}
+
+// toFloat64 converts any integer or float value to float64
+func toFloat64(v interface{}) (float64, bool) {
+	if v == nil {
+		return 0, false
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// toInteger returns value of any integer as int64 or uint64, ok is false for other values
+func toInteger(v interface{}) (i int64, u uint64, unsigned, ok bool) {
+	if v == nil {
+		return 0, 0, false, false
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), 0, false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return 0, rv.Uint(), true, true
+	}
+	return 0, 0, false, false
+}
+
+// numbersEqual reports whether both values are numbers with equal value regardless of their types.
+// Integers are compared exactly, floats are compared as float64, or with float32 precision if any of them is float32
+func numbersEqual(a, b interface{}) bool {
+	if ia, ua, aUnsigned, ok := toInteger(a); ok {
+		if ib, ub, bUnsigned, ok := toInteger(b); ok {
+			switch {
+			case aUnsigned && bUnsigned:
+				return ua == ub
+			case aUnsigned:
+				return ib >= 0 && uint64(ib) == ua
+			case bUnsigned:
+				return ia >= 0 && uint64(ia) == ub
+			}
+			return ia == ib
+		}
+	}
+	fa, ok := toFloat64(a)
+	if !ok {
+		return false
+	}
+	fb, ok := toFloat64(b)
+	if !ok {
+		return false
+	}
+	_, aIsFloat32 := a.(float32)
+	_, bIsFloat32 := b.(float32)
+	if aIsFloat32 || bIsFloat32 {
+		return float32(fa) == float32(fb)
+	}
+	return fa == fb
+}
+
+// lessValue reports whether a goes before b. Numbers, strings, bools and times are compared
+// by value, values of different kinds are grouped: nil, bools, numbers, strings, times, others
+func lessValue(a, b interface{}) bool {
+	rankA, rankB := valueRank(a), valueRank(b)
+	if rankA != rankB {
+		return rankA < rankB
+	}
+	switch rankA {
+	case rankBool:
+		return !a.(bool) && b.(bool)
+	case rankNumber:
+		fa, _ := toFloat64(a)
+		fb, _ := toFloat64(b)
+		return fa < fb
+	case rankString:
+		return toString(a) < toString(b)
+	case rankTime:
+		return a.(time.Time).Before(b.(time.Time))
+	}
+	return false
+}
+
+func toString(v interface{}) string {
+	if bs, ok := v.([]byte); ok {
+		return string(bs)
+	}
+	return v.(string)
+}
+
+// isScannable reports whether database/sql can convert the value emitted by rows into common destinations
+func isScannable(v interface{}) bool {
+	if driver.IsValue(v) {
+		return true
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isNilPointer reports whether v is a typed nil pointer
+func isNilPointer(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}